@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
 	"net/http"
 	"os"
 	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	cli "github.com/jawher/mow.cli"
@@ -13,6 +17,7 @@ import (
 	"github.com/Financial-Times/go-logger"
 	"github.com/Financial-Times/nativerw/pkg/config"
 	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/maintenance"
 	"github.com/Financial-Times/nativerw/pkg/resources"
 	status "github.com/Financial-Times/service-status-go/httphandlers"
 	"github.com/Financial-Times/upp-go-sdk/pkg/documentdb"
@@ -21,6 +26,8 @@ import (
 const (
 	appName        = "nativerw"
 	appDescription = "Writes any raw content/data from native CMS in mongoDB without transformation."
+
+	purgeConfirmationTTL = 5 * time.Minute
 )
 
 func main() {
@@ -65,6 +72,26 @@ func main() {
 		EnvVar: "DISABLE_PURGE",
 	})
 
+	disableCompaction := cliApp.Bool(cli.BoolOpt{
+		Name:   "disable_compaction",
+		Value:  true,
+		Desc:   "Disable the compaction endpoint (true/false)",
+		EnvVar: "DISABLE_COMPACTION",
+	})
+
+	purgeConfirmation := cliApp.Bool(cli.BoolOpt{
+		Name:   "purge_confirmation",
+		Value:  false,
+		Desc:   "Require purges to be confirmed with the token returned by a first call (true/false)",
+		EnvVar: "PURGE_CONFIRMATION",
+	})
+	purgeConfirmationSecret := cliApp.String(cli.StringOpt{
+		Name:   "purge_confirmation_secret",
+		Value:  "",
+		Desc:   "Secret signing the purge confirmation tokens, shared by all the instances of the service",
+		EnvVar: "PURGE_CONFIRMATION_SECRET",
+	})
+
 	logger.InitLogger(appName, "info")
 
 	cliApp.Action = func() {
@@ -77,19 +104,21 @@ func main() {
 		logger.ServiceStartedEvent(conf.Server.Port)
 		tidsToSkipRegex := regexp.MustCompile(*tidsToSkip)
 
-		docdb := documentdb.ConnectionParams{
-			Host:     *dbAddress,
-			Username: *dbUsername,
-			Password: *dbPassword,
-			Database: conf.DBName,
-			UseSrv:   true,
+		mongo := connect(conf, *dbAddress, *dbUsername, *dbPassword)
+
+		var confirmation *resources.PurgeConfirmation
+		if *purgeConfirmation {
+			confirmation = resources.NewPurgeConfirmation(purgeConfirmationKey(*purgeConfirmationSecret), purgeConfirmationTTL)
 		}
-		mongo, err := db.NewDBConnection(docdb, conf.Collections)
-		if err != nil {
-			logger.WithError(err).
-				Fatal("Unable to connect to DocumentDB")
+
+		var retention *maintenance.RetentionJob
+		if policies := conf.Retention(); len(policies) > 0 && conf.Maintenance.Interval.Duration > 0 {
+			retention = maintenance.NewRetentionJob(mongo, policies, conf.Maintenance.Interval.Duration, conf.Maintenance.DryRun)
+			go retention.Start(context.Background())
+		} else if len(policies) > 0 {
+			logger.Warn("Retention policies are configured but maintenance.interval is not set, so the retention job is disabled")
 		}
-		router(mongo, tidsToSkipRegex, *disablePurge)
+		router(mongo, tidsToSkipRegex, *disablePurge, *disableCompaction, confirmation, retention, conf.SkipUnchanged(), contentLimits(conf).Max, compressionThreshold(conf))
 
 		go func() {
 			logger.Info("Established connection to mongoDB.")
@@ -102,13 +131,128 @@ func main() {
 		}
 	}
 
+	cliApp.Command("compact", "Removes the revisions identical to the revision before them, and reports the space reclaimed", func(cmd *cli.Cmd) {
+		collections := cmd.Strings(cli.StringsOpt{
+			Name:  "collection",
+			Value: []string{},
+			Desc:  "Collection to compact, can be repeated. All the collections by default",
+		})
+		dryRun := cmd.Bool(cli.BoolOpt{
+			Name:  "dry_run",
+			Value: false,
+			Desc:  "Only count the duplicate revisions (true/false)",
+		})
+
+		cmd.Action = func() {
+			conf, err := config.ReadConfig(*configFile)
+			if err != nil {
+				logger.WithError(err).Fatal("Error reading the configuration")
+			}
+			if len(*collections) == 0 {
+				*collections = conf.Collections
+			}
+
+			mongo := connect(conf, *dbAddress, *dbUsername, *dbPassword)
+
+			report := map[string]maintenance.CompactionResults{}
+			for _, collection := range *collections {
+				results, err := maintenance.Compact(context.Background(), mongo, collection, *dryRun, func(results maintenance.CompactionResults) {
+					logger.WithField("collection", collection).WithField("documents", results.Documents).Info("Compacting")
+				})
+				report[collection] = results
+				if err != nil {
+					logger.WithError(err).WithField("collection", collection).Fatal("Compaction failed")
+				}
+				logger.WithField("collection", collection).
+					WithField("dryRun", *dryRun).
+					WithField("duplicates", results.Duplicates).
+					WithField("reclaimed", results.Reclaimed).
+					Info("Compaction finished")
+			}
+
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				logger.WithError(err).Fatal("Unable to write the compaction report")
+			}
+		}
+	})
+
+	cliApp.Command("backfill-hashes", "Stores the content hash of the revisions written before hashes were stored", func(cmd *cli.Cmd) {
+		collections := cmd.Strings(cli.StringsOpt{
+			Name:  "collection",
+			Value: []string{},
+			Desc:  "Collection to backfill, can be repeated. All the collections by default",
+		})
+
+		cmd.Action = func() {
+			conf, err := config.ReadConfig(*configFile)
+			if err != nil {
+				logger.WithError(err).Fatal("Error reading the configuration")
+			}
+			if len(*collections) == 0 {
+				*collections = conf.Collections
+			}
+
+			mongo := connect(conf, *dbAddress, *dbUsername, *dbPassword)
+			for _, collection := range *collections {
+				updated, err := mongo.BackfillHashes(context.Background(), collection, resources.HashResource)
+				if err != nil {
+					logger.WithError(err).WithField("collection", collection).WithField("revisions", updated).Fatal("Backfilling the content hashes failed")
+				}
+				logger.WithField("collection", collection).WithField("revisions", updated).Info("Backfilled the content hashes")
+			}
+		}
+	})
+
 	err := cliApp.Run(os.Args)
 	if err != nil {
 		println(err)
 	}
 }
 
-func router(mongo db.Connection, tidsToSkipRegex *regexp.Regexp, disablePurge bool) {
+func connect(conf *config.Configuration, address, username, password string) db.Connection {
+	docdb := documentdb.ConnectionParams{
+		Host:     address,
+		Username: username,
+		Password: password,
+		Database: conf.DBName,
+		UseSrv:   true,
+	}
+	mongo, err := db.NewDBConnection(docdb, conf.Collections, contentLimits(conf))
+	if err != nil {
+		logger.WithError(err).
+			Fatal("Unable to connect to DocumentDB")
+	}
+	return mongo
+}
+
+func contentLimits(conf *config.Configuration) db.ContentLimits {
+	return db.ContentLimits{Inline: conf.Storage.InlineContentSize, Max: conf.Storage.MaxContentSize}.WithDefaults()
+}
+
+func compressionThreshold(conf *config.Configuration) int {
+	if conf.Compression.Threshold > 0 {
+		return conf.Compression.Threshold
+	}
+	return resources.DefaultCompressionThreshold
+}
+
+// purgeConfirmationKey falls back to a random secret, which only lets the instance issuing a token confirm the purge
+func purgeConfirmationKey(secret string) []byte {
+	if secret != "" {
+		return []byte(secret)
+	}
+
+	logger.Warn("No purge confirmation secret is set, confirmation tokens will only be accepted by the instance issuing them")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		logger.WithError(err).Fatal("Unable to generate the purge confirmation secret")
+	}
+	return key
+}
+
+func router(mongo db.Connection, tidsToSkipRegex *regexp.Regexp, disablePurge bool, disableCompaction bool, purgeConfirmation *resources.PurgeConfirmation, retention *maintenance.RetentionJob, skipUnchanged map[string]bool, maxContentSize int64, compressionThreshold int) {
 	ts := resources.CurrentTimestampCreator{}
 
 	r := mux.NewRouter()
@@ -116,29 +260,64 @@ func router(mongo db.Connection, tidsToSkipRegex *regexp.Regexp, disablePurge bo
 	r.HandleFunc("/{collection}/__ids",
 		resources.Filter(resources.ReadIDs(mongo)).
 			ValidateAccessForCollection(mongo).
+			CompressResponse(compressionThreshold).
 			Build()).
 		Methods("GET")
+	if !disablePurge {
+		r.HandleFunc("/{collection}/__purge",
+			resources.Filter(resources.BulkPurgeContent(mongo, &ts)).
+				ValidateAccessForCollection(mongo).
+				ValidateHeader(resources.RequestedByHeader).
+				SkipSpecificRequests(tidsToSkipRegex).
+				Build()).
+			Methods("POST")
+	}
+	if !disableCompaction {
+		r.HandleFunc("/{collection}/__compact",
+			resources.Filter(resources.CompactContent(mongo)).
+				ValidateAccessForCollection(mongo).
+				SkipSpecificRequests(tidsToSkipRegex).
+				Build()).
+			Methods("POST")
+	}
 
 	r.HandleFunc("/{collection}/{resource}",
 		resources.Filter(resources.ReadContent(mongo)).
 			ValidateAccess(mongo).
+			CompressResponse(compressionThreshold).
 			Build()).
 		Methods("GET")
 	r.HandleFunc("/{collection}/{resource}/revisions",
 		resources.Filter(resources.ReadRevisions(mongo)).
 			ValidateAccess(mongo).
+			CompressResponse(compressionThreshold).
+			Build()).
+		Methods("GET")
+	r.HandleFunc("/{collection}/{resource}/diff",
+		resources.Filter(resources.DiffRevisions(mongo)).
+			ValidateAccess(mongo).
+			CompressResponse(compressionThreshold).
+			Build()).
+		Methods("GET")
+	r.HandleFunc("/{collection}/{resource}/hash",
+		resources.Filter(resources.VerifyHash(mongo)).
+			ValidateAccess(mongo).
+			CompressResponse(compressionThreshold).
 			Build()).
 		Methods("GET")
 	r.HandleFunc("/{collection}/{resource}/{revision}",
 		resources.Filter(resources.ReadSingleRevision(mongo)).
 			ValidateAccess(mongo).
+			CompressResponse(compressionThreshold).
 			Build()).
 		Methods("GET")
 	r.HandleFunc("/{collection}/{resource}",
-		resources.Filter(resources.WriteContent(mongo, &ts)).
+		resources.Filter(resources.WriteContent(mongo, &ts, skipUnchanged)).
 			ValidateAccess(mongo).
 			CheckNativeHash(mongo).
 			ValidateHeader(resources.SchemaVersionHeader).
+			LimitBodySize(maxContentSize).
+			DecodeContent().
 			SkipSpecificRequests(tidsToSkipRegex).
 			Build()).
 		Methods("POST")
@@ -147,11 +326,26 @@ func router(mongo db.Connection, tidsToSkipRegex *regexp.Regexp, disablePurge bo
 			ValidateAccess(mongo).
 			CheckNativeHash(mongo).
 			ValidateHeader(resources.SchemaVersionHeader).
+			LimitBodySize(maxContentSize).
+			DecodeContent().
 			SkipSpecificRequests(tidsToSkipRegex).
 			Build()).
 		Methods("PATCH")
+	r.HandleFunc("/{collection}/{resource}/undelete",
+		resources.Filter(resources.UndeleteContent(mongo, &ts)).
+			ValidateAccess(mongo).
+			SkipSpecificRequests(tidsToSkipRegex).
+			Build()).
+		Methods("POST")
+	r.HandleFunc("/{collection}/{resource}/{revision}/restore",
+		resources.Filter(resources.RestoreContent(mongo, &ts)).
+			ValidateAccess(mongo).
+			CheckNativeHash(mongo).
+			SkipSpecificRequests(tidsToSkipRegex).
+			Build()).
+		Methods("POST")
 	r.HandleFunc("/{collection}/{resource}",
-		resources.Filter(resources.WriteContent(mongo, &ts)).
+		resources.Filter(resources.DeleteContent(mongo, &ts)).
 			ValidateAccess(mongo).
 			ValidateHeader(resources.SchemaVersionHeader).
 			SkipSpecificRequests(tidsToSkipRegex).
@@ -159,14 +353,29 @@ func router(mongo db.Connection, tidsToSkipRegex *regexp.Regexp, disablePurge bo
 		Methods("DELETE")
 
 	if !disablePurge {
+		r.HandleFunc("/{collection}/purge/{resource}",
+			resources.Filter(resources.PurgeAllContent(mongo, &ts)).
+				ConfirmPurge(mongo, purgeConfirmation).
+				ValidateAccess(mongo).
+				ValidateHeader(resources.RequestedByHeader).
+				SkipSpecificRequests(tidsToSkipRegex).
+				Build()).
+			Methods("DELETE")
 		r.HandleFunc("/{collection}/purge/{resource}/{revision}",
 			resources.Filter(resources.PurgeContent(mongo)).
+				ConfirmPurge(mongo, purgeConfirmation).
 				ValidateAccess(mongo).
 				SkipSpecificRequests(tidsToSkipRegex).
 				Build()).
 			Methods("DELETE")
 	}
 
+	if retention != nil {
+		r.HandleFunc("/__retention", retention.StatusHandler).Methods("GET")
+	}
+
+	r.HandleFunc("/__content-types", resources.ReadContentTypes).Methods("GET")
+
 	r.HandleFunc("/__health", resources.Healthchecks(mongo))
 	r.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(resources.GoodToGo(mongo)))
 
@@ -2,8 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Server config struct
@@ -13,9 +17,98 @@ type Server struct {
 
 // Configuration data
 type Configuration struct {
-	DBName      string   `json:"dbName"`
-	Server      Server   `json:"server"`
-	Collections []string `json:"collections"`
+	DBName            string                       `json:"dbName"`
+	Server            Server                       `json:"server"`
+	Collections       []string                     `json:"collections"`
+	CollectionOptions map[string]CollectionOptions `json:"collectionOptions,omitempty"`
+	Maintenance       Maintenance                  `json:"maintenance"`
+	Storage           Storage                      `json:"storage"`
+	Compression       Compression                  `json:"compression"`
+}
+
+// CollectionOptions holds the settings specific to a collection
+type CollectionOptions struct {
+	Retention     *Retention `json:"retention,omitempty"`
+	SkipUnchanged bool       `json:"skipUnchanged,omitempty"`
+}
+
+// Retention limits the revisions kept for every document of a collection.
+// A revision is kept if any of the rules keeps it, and the latest revision and deletions are always kept.
+type Retention struct {
+	KeepLast      int      `json:"keepLast,omitempty"`
+	KeepNewerThan Duration `json:"keepNewerThan,omitempty"`
+}
+
+// Maintenance config struct for the background jobs. The jobs are disabled when the interval is zero.
+type Maintenance struct {
+	Interval Duration `json:"interval,omitempty"`
+	DryRun   bool     `json:"dryRun,omitempty"`
+}
+
+// Storage config struct for the size of the contents, in bytes. Zero values use the defaults.
+// A content larger than InlineContentSize is stored in GridFS, and one larger than MaxContentSize is rejected.
+type Storage struct {
+	InlineContentSize int64 `json:"inlineContentSize,omitempty"`
+	MaxContentSize    int64 `json:"maxContentSize,omitempty"`
+}
+
+// Compression config struct for the responses, which are compressed from Threshold bytes when the client accepts it. A zero value uses the default.
+type Compression struct {
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// Retention returns the retention rules of every collection which has some
+func (c *Configuration) Retention() map[string]Retention {
+	policies := map[string]Retention{}
+	for collection, options := range c.CollectionOptions {
+		if options.Retention != nil {
+			policies[collection] = *options.Retention
+		}
+	}
+	return policies
+}
+
+// SkipUnchanged returns the collections where writing a record identical to the latest revision is skipped
+func (c *Configuration) SkipUnchanged() map[string]bool {
+	collections := map[string]bool{}
+	for collection, options := range c.CollectionOptions {
+		if options.SkipUnchanged {
+			collections[collection] = true
+		}
+	}
+	return collections
+}
+
+// Duration is a time.Duration written as a string in JSON, e.g. "36h", with "d" as an additional unit for days, e.g. "30d"
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if days, found := strings.CutSuffix(s, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		d.Duration = time.Duration(n) * 24 * time.Hour
+		return nil
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
 }
 
 // ReadConfigFromReader reads config as a json stream from the given reader
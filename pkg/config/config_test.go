@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -64,3 +65,66 @@ func TestConfigFromFile(t *testing.T) {
 	assert.Equal(t, []string{"video", "universal-content", "pac-metadata", "manual-metadata", "content-relation"}, config.Collections)
 	assert.Equal(t, 8080, config.Server.Port)
 }
+
+func TestConfigWithRetention(t *testing.T) {
+	reader := strings.NewReader(`{
+         "dbName": "native-store",
+         "collections": ["universal-content", "draft-annotations", "video"],
+         "collectionOptions": {
+            "draft-annotations": {
+               "retention": {"keepLast": 20, "keepNewerThan": "30d"}
+            },
+            "video": {
+               "retention": {"keepNewerThan": "36h"}
+            },
+            "universal-content": {}
+         },
+         "maintenance": {"interval": "1h", "dryRun": true}
+      }`)
+	config, err := ReadConfigFromReader(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Retention{
+		"draft-annotations": {KeepLast: 20, KeepNewerThan: Duration{30 * 24 * time.Hour}},
+		"video":             {KeepNewerThan: Duration{36 * time.Hour}},
+	}, config.Retention())
+	assert.Equal(t, time.Hour, config.Maintenance.Interval.Duration)
+	assert.True(t, config.Maintenance.DryRun)
+}
+
+func TestConfigWithInvalidDuration(t *testing.T) {
+	reader := strings.NewReader(`{"maintenance": {"interval": "weekly"}}`)
+	_, err := ReadConfigFromReader(reader)
+
+	assert.Error(t, err)
+}
+
+func TestConfigWithSkipUnchanged(t *testing.T) {
+	reader := strings.NewReader(`{
+         "collections": ["universal-content", "video"],
+         "collectionOptions": {
+            "universal-content": {"skipUnchanged": true},
+            "video": {"retention": {"keepLast": 2}}
+         }
+      }`)
+	config, err := ReadConfigFromReader(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"universal-content": true}, config.SkipUnchanged())
+}
+
+func TestConfigWithStorage(t *testing.T) {
+	reader := strings.NewReader(`{"storage": {"inlineContentSize": 4194304, "maxContentSize": 33554432}}`)
+	config, err := ReadConfigFromReader(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Storage{InlineContentSize: 4194304, MaxContentSize: 33554432}, config.Storage)
+}
+
+func TestConfigWithCompression(t *testing.T) {
+	reader := strings.NewReader(`{"compression": {"threshold": 4096}}`)
+	config, err := ReadConfigFromReader(reader)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Compression{Threshold: 4096}, config.Compression)
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const auditCollection = "purge-audit"
+
+// AuditEntry records who erased native content, and when.
+// Entries are only ever inserted, so the audit trail cannot be altered through the service.
+type AuditEntry struct {
+	Action        string
+	Collection    string
+	UUID          string
+	Filter        string
+	Revisions     int
+	RequestedBy   string
+	TransactionID string
+	Time          time.Time
+}
+
+// insertAudit inserts the entry in the audit collection, within the transaction of the context if it has one
+func (ma *MongoConnection) insertAudit(ctx context.Context, entry AuditEntry) error {
+	coll := ma.client.Database(ma.dbName).Collection(auditCollection)
+	document := bson.M{
+		"action":         entry.Action,
+		"collection":     entry.Collection,
+		"revisions":      entry.Revisions,
+		"requested-by":   entry.RequestedBy,
+		"transaction-id": entry.TransactionID,
+		"time":           entry.Time,
+	}
+	if entry.UUID != "" {
+		document["uuid"] = entry.UUID
+	}
+	if entry.Filter != "" {
+		document["filter"] = entry.Filter
+	}
+
+	_, err := coll.InsertOne(ctx, document)
+	return err
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+// BackfillHashes stores the missing content hashes and sizes of the revisions written before they were stored, and returns how many were updated.
+// The hash function fills in the hashes which are empty. Tombstones have no content, so they are left without hashes.
+func (ma *MongoConnection) BackfillHashes(ctx context.Context, collection string, hash func(resource *mapper.Resource) error) (int64, error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+
+	query := bson.M{
+		"$or": []bson.M{
+			{contentHashName: bson.M{"$exists": false}},
+			{canonicalHashName: bson.M{"$exists": false}},
+			{contentSizeName: bson.M{"$exists": false}},
+		},
+		deletedName: bson.M{"$ne": true},
+	}
+	opts := options.Find().
+		SetProjection(bson.M{"_id": 1, contentName: 1, contentFileName: 1, contentNumbersName: 1, contentHashName: 1, canonicalHashName: 1, contentSizeName: 1}).
+		SetBatchSize(100)
+	cur, err := coll.Find(ctx, query, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var updated int64
+	for cur.Next(ctx) {
+		var result map[string]interface{}
+		if err := cur.Decode(&result); err != nil {
+			return updated, err
+		}
+
+		if _, err := ma.loadContent(collection, result); err != nil {
+			return updated, err
+		}
+
+		resource := &mapper.Resource{Content: contentFromBSON(result[contentName], result[contentNumbersName])}
+		resource.ContentHash, _ = result[contentHashName].(string)
+		resource.CanonicalHash, _ = result[canonicalHashName].(string)
+		resource.ContentSize, _ = result[contentSizeName].(int64)
+		if err := hash(resource); err != nil {
+			return updated, err
+		}
+
+		update := bson.M{"$set": bson.M{contentHashName: resource.ContentHash, canonicalHashName: resource.CanonicalHash, contentSizeName: resource.ContentSize}}
+		if _, err := coll.UpdateOne(ctx, bson.M{"_id": result["_id"]}, update); err != nil {
+			return updated, err
+		}
+		updated++
+	}
+
+	return updated, cur.Err()
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	driverbson "go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+const (
+	contentName        = "content"
+	contentFileName    = "content-file"
+	contentNumbersName = "content-numbers"
+
+	// DefaultInlineContentSize keeps the revision documents well below the 16MB limit of DocumentDB
+	DefaultInlineContentSize = 8 << 20
+	// DefaultMaxContentSize is the largest content accepted when no limit is configured
+	DefaultMaxContentSize = 64 << 20
+
+	mongoContentTransferTimeout = time.Second * 30
+)
+
+// ErrContentTooLarge is returned when writing a content larger than the maximum content size
+var ErrContentTooLarge = errors.New("content too large")
+
+// ContentLimits sets where a content is stored depending on the size of its BSON encoding.
+// A content larger than Inline bytes is stored in GridFS, in the bucket named after the collection, and the revision document only holds a reference to it.
+// A content larger than Max bytes is rejected with ErrContentTooLarge. Zero values select the defaults.
+type ContentLimits struct {
+	Inline int64
+	Max    int64
+}
+
+// WithDefaults returns the limits with the zero values replaced by the defaults
+func (l ContentLimits) WithDefaults() ContentLimits {
+	if l.Inline <= 0 {
+		l.Inline = DefaultInlineContentSize
+	}
+	if l.Max <= 0 {
+		l.Max = DefaultMaxContentSize
+	}
+	return l
+}
+
+// storedContent is a content as written in the store, either inline in the revision document or in a GridFS file,
+// with the literals of the numbers its BSON encoding does not keep
+type storedContent struct {
+	inline  interface{}
+	numbers []interface{}
+	fileID  interface{}
+}
+
+// files returns the GridFS file holding the content, if any
+func (c storedContent) files() []interface{} {
+	if c.fileID == nil {
+		return nil
+	}
+	return []interface{}{c.fileID}
+}
+
+func (ma *MongoConnection) bucket(collection string) (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(ma.client.Database(ma.dbName), options.GridFSBucket().SetName(collection))
+}
+
+// storeContent encodes the content of a resource, and uploads it to GridFS if it is too large to be stored inline.
+// The file is named after the document and the revision, but it is only referenced by its id.
+func (ma *MongoConnection) storeContent(collection string, resource *mapper.Resource) (storedContent, error) {
+	content := storedContent{inline: toBSONNumbers(resource.Content), numbers: numberLiterals(resource.Content)}
+	if resource.Content == nil {
+		return content, nil
+	}
+
+	encoded := map[string]interface{}{contentName: content.inline}
+	if content.numbers != nil {
+		encoded[contentNumbersName] = content.numbers
+	}
+	data, err := driverbson.Marshal(encoded)
+	if err != nil {
+		return content, err
+	}
+
+	limits := ma.contentLimits.WithDefaults()
+	size := int64(len(data))
+	if size > limits.Max {
+		return content, fmt.Errorf("%w: %d bytes, the maximum is %d bytes", ErrContentTooLarge, size, limits.Max)
+	}
+	if size <= limits.Inline {
+		return content, nil
+	}
+
+	bucket, err := ma.bucket(collection)
+	if err != nil {
+		return content, err
+	}
+	if err := bucket.SetWriteDeadline(time.Now().Add(mongoContentTransferTimeout)); err != nil {
+		return content, err
+	}
+
+	name := fmt.Sprintf("%s/%d", resource.UUID, resource.ContentRevision)
+	fileID, err := bucket.UploadFromStream(name, bytes.NewReader(data))
+	if err != nil {
+		return content, err
+	}
+	return storedContent{fileID: fileID}, nil
+}
+
+// loadContent replaces the reference to a content stored in GridFS with the content itself, and returns the size of the file
+func (ma *MongoConnection) loadContent(collection string, bsonResource map[string]interface{}) (int64, error) {
+	fileID, found := bsonResource[contentFileName]
+	if !found {
+		return 0, nil
+	}
+
+	bucket, err := ma.bucket(collection)
+	if err != nil {
+		return 0, err
+	}
+	if err := bucket.SetReadDeadline(time.Now().Add(mongoContentTransferTimeout)); err != nil {
+		return 0, err
+	}
+
+	var data bytes.Buffer
+	size, err := bucket.DownloadToStream(fileID, &data)
+	if err != nil {
+		return 0, fmt.Errorf("reading the content file %v: %w", fileID, err)
+	}
+
+	var stored map[string]interface{}
+	if err := driverbson.Unmarshal(data.Bytes(), &stored); err != nil {
+		return 0, err
+	}
+	bsonResource[contentName] = stored[contentName]
+	if numbers, found := stored[contentNumbersName]; found {
+		bsonResource[contentNumbersName] = numbers
+	}
+	return size, nil
+}
+
+// contentFiles returns the GridFS files of the revisions matching the filter, to be removed after the revisions are deleted
+func contentFiles(ctx context.Context, coll *mongo.Collection, filter bson.M) ([]interface{}, error) {
+	query := bson.M{contentFileName: bson.M{"$exists": true}}
+	for k, v := range filter {
+		query[k] = v
+	}
+
+	cur, err := coll.Find(ctx, query, options.Find().SetProjection(bson.M{contentFileName: 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var files []interface{}
+	for cur.Next(ctx) {
+		var result map[string]interface{}
+		if err := cur.Decode(&result); err != nil {
+			return nil, err
+		}
+		files = append(files, result[contentFileName])
+	}
+	return files, cur.Err()
+}
+
+// removeContentFiles deletes GridFS files which are no longer referenced. Failures are only logged, as they leave unused files behind but no missing content.
+func (ma *MongoConnection) removeContentFiles(ctx context.Context, collection string, files []interface{}) {
+	if len(files) == 0 {
+		return
+	}
+
+	bucket, err := ma.bucket(collection)
+	if err != nil {
+		logger.WithError(err).Warnf("Unable to remove %d content files from %s", len(files), collection)
+		return
+	}
+
+	for _, fileID := range files {
+		if err := bucket.DeleteContext(ctx, fileID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+			logger.WithError(err).WithField("file", fileID).Warnf("Unable to remove a content file from %s", collection)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestContentLimitsWithDefaults(t *testing.T) {
+	assert.Equal(t, ContentLimits{Inline: DefaultInlineContentSize, Max: DefaultMaxContentSize}, ContentLimits{}.WithDefaults())
+	assert.Equal(t, ContentLimits{Inline: 1024, Max: DefaultMaxContentSize}, ContentLimits{Inline: 1024}.WithDefaults())
+}
+
+func startMongoWithLimits(t *testing.T, limits ContentLimits) *MongoConnection {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	mongo := connection.(*MongoConnection)
+	mongo.contentLimits = limits
+	return mongo
+}
+
+func countContentFiles(t *testing.T, connection *MongoConnection, collection string) int64 {
+	bucket, err := connection.bucket(collection)
+	assert.NoError(t, err)
+
+	n, err := bucket.GetFilesCollection().CountDocuments(context.Background(), bson.M{})
+	assert.NoError(t, err)
+	return n
+}
+
+func TestReadWriteLargeContent(t *testing.T) {
+	connection := startMongoWithLimits(t, ContentLimits{Inline: 1024, Max: 1 << 20})
+
+	expectedResource := generateResource()
+	expectedResource.Content = map[string]interface{}{"body": strings.Repeat("a", 4096)}
+	files := countContentFiles(t, connection, "universal-content")
+
+	err := connection.Write("universal-content", expectedResource)
+	assert.NoError(t, err)
+	assert.Equal(t, files+1, countContentFiles(t, connection, "universal-content"))
+
+	res, found, err := connection.Read("universal-content", expectedResource.UUID)
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResource.Content, res.Content)
+
+	res, err = connection.ReadSingleRevision("universal-content", expectedResource.UUID, expectedResource.ContentRevision)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResource.Content, res.Content)
+
+	err = connection.Delete("universal-content", expectedResource.UUID, expectedResource.ContentRevision)
+	assert.NoError(t, err)
+	assert.Equal(t, files, countContentFiles(t, connection, "universal-content"))
+}
+
+func TestWriteContentTooLarge(t *testing.T) {
+	connection := startMongoWithLimits(t, ContentLimits{Inline: 1024, Max: 2048})
+
+	resource := generateResource()
+	resource.Content = map[string]interface{}{"body": strings.Repeat("a", 4096)}
+
+	err := connection.Write("universal-content", resource)
+	assert.True(t, errors.Is(err, ErrContentTooLarge))
+
+	_, found, err := connection.Read("universal-content", resource.UUID)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestRewriteRevisionRemovesReplacedContentFile(t *testing.T) {
+	connection := startMongoWithLimits(t, ContentLimits{Inline: 1024, Max: 1 << 20})
+
+	resource := generateResource()
+	resource.Content = map[string]interface{}{"body": strings.Repeat("a", 4096)}
+	files := countContentFiles(t, connection, "universal-content")
+
+	err := connection.Write("universal-content", resource)
+	assert.NoError(t, err)
+	resource.Content = map[string]interface{}{"body": strings.Repeat("b", 4096)}
+	err = connection.Write("universal-content", resource)
+	assert.NoError(t, err)
+	assert.Equal(t, files+1, countContentFiles(t, connection, "universal-content"))
+
+	resource.Content = map[string]interface{}{"body": "small"}
+	err = connection.Write("universal-content", resource)
+	assert.NoError(t, err)
+	assert.Equal(t, files, countContentFiles(t, connection, "universal-content"))
+
+	res, found, err := connection.Read("universal-content", resource.UUID)
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Content, res.Content)
+
+	err = connection.Delete("universal-content", resource.UUID, resource.ContentRevision)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const leaseCollection = "leases"
+
+// AcquireLease takes or renews the lease with the given name for ttl, and reports whether the holder got it.
+// A lease held by another holder is only taken once it has expired.
+// Each lease is a single document, upserted atomically, so that only one of the instances asking at the same time gets it.
+func (ma *MongoConnection) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	coll := ma.client.Database(ma.dbName).Collection(leaseCollection)
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"holder": holder},
+			{"expires": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"holder": holder, "expires": now.Add(ttl)}}
+
+	// the lease held by another holder does not match, and inserting it again conflicts on its _id
+	_, err := coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReleaseLease lets the lease held by holder expire at the given time, or straight away if that time has passed, so that another holder can take it from then on.
+// A lease held by another holder is left as it is.
+func (ma *MongoConnection) ReleaseLease(ctx context.Context, name string, holder string, expires time.Time) error {
+	coll := ma.client.Database(ma.dbName).Collection(leaseCollection)
+
+	filter := bson.M{"_id": name, "holder": holder}
+	update := bson.M{"$set": bson.M{"expires": expires.UTC()}}
+	_, err := coll.UpdateOne(ctx, filter, update)
+	return err
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcquireLease(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	name := "test-" + uuid.New()
+
+	leased, err := connection.AcquireLease(ctx, name, "first", 200*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, leased)
+
+	leased, err = connection.AcquireLease(ctx, name, "second", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, leased, "the lease is held by the first holder")
+
+	leased, err = connection.AcquireLease(ctx, name, "first", 200*time.Millisecond)
+	assert.NoError(t, err)
+	assert.True(t, leased, "the holder renews its lease")
+
+	time.Sleep(300 * time.Millisecond)
+	leased, err = connection.AcquireLease(ctx, name, "second", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, leased, "an expired lease is taken over")
+
+	err = connection.ReleaseLease(ctx, name, "first", time.Now())
+	assert.NoError(t, err)
+	leased, err = connection.AcquireLease(ctx, name, "third", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, leased, "only its holder releases a lease")
+
+	err = connection.ReleaseLease(ctx, name, "second", time.Now())
+	assert.NoError(t, err)
+	leased, err = connection.AcquireLease(ctx, name, "third", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, leased, "a released lease is taken over")
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	driverbson "go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -37,3 +38,20 @@ func startMongo(t *testing.T) (Connection, error) {
 		},
 	}, nil
 }
+
+// skipWithoutReplicaSet skips the tests of the operations running in a transaction, which a standalone mongod does not support
+func skipWithoutReplicaSet(t *testing.T, connection Connection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var hello struct {
+		SetName string `bson:"setName"`
+	}
+	err := connection.(*MongoConnection).client.Database("admin").RunCommand(ctx, driverbson.D{{Key: "isMaster", Value: 1}}).Decode(&hello)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hello.SetName == "" {
+		t.Skip("Transactions need a replica set, and MONGO_TEST_URL is a standalone server.")
+	}
+}
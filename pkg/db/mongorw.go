@@ -20,16 +20,25 @@ import (
 const (
 	uuidName            = "uuid"
 	contentRevisionName = "content-revision"
+	supersedesName      = "supersedes"
+	deletedName         = "deleted"
+	contentHashName     = "content-hash"
+	canonicalHashName   = "canonical-hash"
+	contentSizeName     = "content-size"
 
 	mongoConnectionTimeout       = time.Second * 30
 	mongoIndexCreationTimeout    = time.Second * 15
 	mongoDefaultOperationTimeout = time.Second * 5
+
+	// maxWriteAttempts bounds how many times a write is retried when other writers keep superseding the latest revision first
+	maxWriteAttempts = 5
 )
 
 type MongoConnection struct {
-	dbName      string
-	client      *mongo.Client
-	collections map[string]bool
+	dbName        string
+	client        *mongo.Client
+	collections   map[string]bool
+	contentLimits ContentLimits
 }
 
 // Connection contains all mongo request logic, including reads, writes and deletes.
@@ -37,17 +46,29 @@ type Connection interface {
 	EnsureIndex()
 	GetSupportedCollections() map[string]bool
 	Delete(collection string, uuidString string, revision int64) error
+	DeleteAll(collection string, uuidString string, audit AuditEntry) (revisions []int64, err error)
+	DeleteMatching(ctx context.Context, collection string, filter RevisionFilter, batchSize int64, audit AuditEntry) (int64, error)
+	DeleteRevisions(ctx context.Context, collection string, uuidString string, revisions []int64) (int64, error)
 	Write(collection string, resource *mapper.Resource) error
+	WriteConditionally(collection string, resource *mapper.Resource, precondition Precondition) error
 	Read(collection string, uuidString string) (res *mapper.Resource, found bool, err error)
+	ReadMetadata(collection string, uuidString string) (res *mapper.Resource, found bool, err error)
+	ReadAsOf(collection string, uuidString string, asOf int64) (res *mapper.Resource, found bool, err error)
 	ReadSingleRevision(collection string, uuidString string, revision int64) (res *mapper.Resource, err error)
-	ReadIDs(ctx context.Context, collection string) (chan string, error)
+	ReadIDs(ctx context.Context, collection string, includeDeleted bool) (chan string, error)
 	ReadRevisions(collection string, uuidString string) (res []int64, err error)
+	ReadRevisionsPage(collection string, uuidString string, page RevisionPage) (res []*mapper.Resource, more bool, err error)
+	ReadRevisionsMetadata(ctx context.Context, collection string, uuidString string) (res []*mapper.Resource, err error)
+	ReadStoredRevisions(ctx context.Context, collection string, uuidString string) (res []StoredRevision, err error)
 	Count(collection string, uuidString string, contentRevision int64) (count int64, err error)
+	BackfillHashes(ctx context.Context, collection string, hash func(resource *mapper.Resource) error) (int64, error)
+	AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name string, holder string, expires time.Time) error
 	Ping() error
 }
 
-// NewDBConnection dials the mongo cluster, and returns a new handler DB instance
-func NewDBConnection(docDBConf documentdb.ConnectionParams, collections []string) (*MongoConnection, error) {
+// NewDBConnection dials the mongo cluster, and returns a new handler DB instance storing the contents according to the given limits
+func NewDBConnection(docDBConf documentdb.ConnectionParams, collections []string, limits ContentLimits) (*MongoConnection, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), mongoConnectionTimeout)
 	defer cancel()
 	client, err := documentdb.NewClient(ctx, docDBConf)
@@ -56,7 +77,7 @@ func NewDBConnection(docDBConf documentdb.ConnectionParams, collections []string
 	}
 
 	colls := createMapWithAllowedCollections(collections)
-	return &MongoConnection{docDBConf.Database, client, colls}, nil
+	return &MongoConnection{docDBConf.Database, client, colls, limits}, nil
 }
 
 func (ma *MongoConnection) GetSupportedCollections() map[string]bool {
@@ -81,13 +102,24 @@ func (ma *MongoConnection) EnsureIndex() {
 			SetName("uuid-revision-index").
 			SetUnique(true),
 	}
+	// a revision can only be superseded once, which is what makes the conditional writes atomic.
+	// The index is sparse, as the revisions written before it, or out of order, do not record the revision they supersede.
+	supersedesIndex := mongo.IndexModel{
+		Keys: bsonx.Doc{
+			{Key: supersedesName, Value: bsonx.Int32(1)},
+		},
+		Options: options.Index().
+			SetName("supersedes-index").
+			SetUnique(true).
+			SetSparse(true),
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), mongoIndexCreationTimeout)
 	defer cancel()
 
 	for coll := range ma.collections {
 		indexes := ma.client.Database(ma.dbName).Collection(coll).Indexes()
-		if _, err := indexes.CreateOne(ctx, index); err != nil {
+		if _, err := indexes.CreateMany(ctx, []mongo.IndexModel{index, supersedesIndex}); err != nil {
 			logger.WithError(err).Infof("could not EnsureIndex for collection %s", coll)
 		}
 	}
@@ -99,38 +131,282 @@ func (ma *MongoConnection) Delete(collection string, uuidString string, revision
 	defer cancel()
 
 	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
-	_, err := coll.DeleteOne(ctx, bsonx.Doc{
+	files, err := contentFiles(ctx, coll, bson.M{uuidName: bsonUUID, contentRevisionName: revision})
+	if err != nil {
+		return err
+	}
+
+	_, err = coll.DeleteOne(ctx, bsonx.Doc{
 		{Key: uuidName, Value: bsonUUID},
 		{Key: contentRevisionName, Value: bsonx.Int64(revision)},
 	})
+	if err != nil {
+		return err
+	}
+
+	ma.removeContentFiles(ctx, collection, files)
+	return nil
+}
+
+// DeleteAll removes every revision of a document, and returns the revisions removed in ascending order.
+// The audit entry is inserted with the number of revisions removed in the same transaction, so that no erasure happens without its record. Nothing is audited when the document does not exist.
+func (ma *MongoConnection) DeleteAll(collection string, uuidString string, audit AuditEntry) (revisions []int64, err error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
+	defer cancel()
+
+	session, err := ma.client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
+	var files []interface{}
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		opts := options.Find().
+			SetProjection(bson.M{contentRevisionName: 1, contentFileName: 1}).
+			SetSort(bsonx.Doc{
+				{Key: contentRevisionName, Value: bsonx.Int32(1)},
+			})
+		cur, err := coll.Find(sc, bson.M{uuidName: bsonUUID}, opts)
+		if err != nil {
+			return nil, err
+		}
+		defer cur.Close(sc)
+
+		revisions = []int64{}
+		for cur.Next(sc) {
+			var bsonResource map[string]interface{}
+			if err := cur.Decode(&bsonResource); err != nil {
+				return nil, err
+			}
+			revision, _ := bsonResource[contentRevisionName].(int64)
+			revisions = append(revisions, revision)
+			if fileID, found := bsonResource[contentFileName]; found {
+				files = append(files, fileID)
+			}
+		}
+		if err := cur.Err(); err != nil {
+			return nil, err
+		}
+
+		if len(revisions) == 0 {
+			return nil, nil
+		}
+
+		if _, err = coll.DeleteMany(sc, bson.M{uuidName: bsonUUID}); err != nil {
+			return nil, err
+		}
+		audit.Revisions = len(revisions)
+		return nil, ma.insertAudit(sc, audit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ma.removeContentFiles(ctx, collection, files)
+	return revisions, nil
+}
+
+// DeleteRevisions removes the given revisions of a document, and returns how many were removed
+func (ma *MongoConnection) DeleteRevisions(ctx context.Context, collection string, uuidString string, revisions []int64) (int64, error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
+	filter := bson.M{
+		uuidName:            bsonUUID,
+		contentRevisionName: bson.M{"$in": revisions},
+	}
+	files, err := contentFiles(ctx, coll, filter)
+	if err != nil {
+		return 0, err
+	}
 
-	return err
+	result, err := coll.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	ma.removeContentFiles(ctx, collection, files)
+	return result.DeletedCount, nil
 }
 
+// Write writes the resource as a new revision, whatever the latest stored revision.
+// Like the conditional writes, the new revision records the latest revision it supersedes, so that the supersedes-index also
+// detects the conditional writes racing with it. When another writer supersedes the same revision first, the latest revision is read again and the write retried.
 func (ma *MongoConnection) Write(collection string, resource *mapper.Resource) error {
 	coll := ma.client.Database(ma.dbName).Collection(collection)
+	content, err := ma.storeContent(collection, resource)
+	if err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
 	defer cancel()
 
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(resource.UUID))
+	var replaced []interface{}
+	for attempt := 1; ; attempt++ {
+		latest, _, found, err := latestRevision(ctx, coll, bsonUUID)
+		if err == nil {
+			replaced, err = upsertResource(ctx, coll, resource, content, supersededRevision(latest, found, resource.ContentRevision))
+		}
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) || attempt == maxWriteAttempts {
+			ma.removeContentFiles(ctx, collection, content.files())
+			return err
+		}
+	}
+
+	ma.removeContentFiles(ctx, collection, replaced)
+	return nil
+}
+
+// WriteConditionally writes the resource only if the latest stored revision satisfies the precondition.
+// The new revision records the revision it supersedes, or 0 for the first one, and the supersedes-index only allows a revision to be superseded once,
+// so concurrent writers conflict on the same revision and cannot both succeed. A single document is written, so no transaction is needed.
+func (ma *MongoConnection) WriteConditionally(collection string, resource *mapper.Resource, precondition Precondition) error {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
+	defer cancel()
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(resource.UUID))
+	latest, latestHash, found, err := latestRevision(ctx, coll, bsonUUID)
+	if err != nil {
+		return err
+	}
+	if !precondition.SatisfiedBy(latest, latestHash, found) {
+		return ErrPreconditionFailed
+	}
+
+	content, err := ma.storeContent(collection, resource)
+	if err != nil {
+		return err
+	}
+
+	replaced, err := upsertResource(ctx, coll, resource, content, supersededRevision(latest, found, resource.ContentRevision))
+	if err != nil {
+		ma.removeContentFiles(ctx, collection, content.files())
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrPreconditionFailed
+		}
+		return err
+	}
+
+	ma.removeContentFiles(ctx, collection, replaced)
+	return nil
+}
+
+// supersededRevision returns the revision a new revision supersedes: the latest stored revision, or 0 for the first one.
+// A revision written again, or written out of order before the latest one, supersedes nothing, and keeps what it recorded if it was already stored.
+func supersededRevision(latest int64, found bool, revision int64) *int64 {
+	if !found {
+		var first int64
+		return &first
+	}
+	if latest >= revision {
+		return nil
+	}
+	return &latest
+}
+
+// upsertResource writes a revision, recording the revision it supersedes if given, and returns the GridFS file of the content it replaces
+// when the revision is written again, to be removed once the write is done
+func upsertResource(ctx context.Context, coll *mongo.Collection, resource *mapper.Resource, content storedContent, previousRevision *int64) (replacedFiles []interface{}, err error) {
 	bsonUUID := bsonx.Binary(0x04, uuid.Parse(resource.UUID))
 
 	bsonResource := map[string]interface{}{
 		"uuid":             bsonUUID,
-		"content":          resource.Content,
 		"content-type":     resource.ContentType,
 		"origin-system-id": resource.OriginSystemID,
 		"schema-version":   resource.SchemaVersion,
 		"content-revision": resource.ContentRevision,
 	}
+	if resource.Deleted {
+		bsonResource[deletedName] = true
+	}
+	if resource.ContentHash != "" {
+		bsonResource[contentHashName] = resource.ContentHash
+		bsonResource[contentSizeName] = resource.ContentSize
+	}
+	if resource.CanonicalHash != "" {
+		bsonResource[canonicalHashName] = resource.CanonicalHash
+	}
+	if previousRevision != nil {
+		bsonResource[supersedesName] = bsonx.Doc{
+			{Key: uuidName, Value: bsonUUID},
+			{Key: contentRevisionName, Value: bsonx.Int64(*previousRevision)},
+		}
+	}
+	// a revision written again only keeps the content it was last written with
+	unset := bson.M{}
+	if content.fileID != nil {
+		bsonResource[contentFileName] = content.fileID
+		unset[contentName] = ""
+		unset[contentNumbersName] = ""
+	} else {
+		bsonResource[contentName] = content.inline
+		unset[contentFileName] = ""
+		if content.numbers != nil {
+			bsonResource[contentNumbersName] = content.numbers
+		} else {
+			unset[contentNumbersName] = ""
+		}
+	}
 	filter := bson.M{
 		uuidName:            bsonUUID,
 		contentRevisionName: resource.ContentRevision,
 	}
-	update := bson.M{"$set": bsonResource}
-	opts := options.Update().SetUpsert(true)
-	_, err := coll.UpdateOne(ctx, filter, update, opts)
+	update := bson.M{"$set": bsonResource, "$unset": unset}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.Before).
+		SetProjection(bson.M{contentFileName: 1})
+	result := coll.FindOneAndUpdate(ctx, filter, update, opts)
+	if err := result.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
 
-	return err
+	var replaced map[string]interface{}
+	if err := result.Decode(&replaced); err != nil {
+		return nil, err
+	}
+	if fileID, found := replaced[contentFileName]; found {
+		return []interface{}{fileID}, nil
+	}
+	return nil, nil
+}
+
+// latestRevision reads the latest revision of a document with its content hash, which is empty for tombstones and revisions written before hashes were stored
+func latestRevision(ctx context.Context, coll *mongo.Collection, bsonUUID bsonx.Val) (revision int64, hash string, found bool, err error) {
+	opts := options.FindOne().
+		SetSort(bsonx.Doc{
+			{Key: contentRevisionName, Value: bsonx.Int32(-1)},
+		}).
+		SetProjection(bson.M{contentRevisionName: 1, contentHashName: 1})
+	result := coll.FindOne(ctx, bson.M{uuidName: bsonUUID}, opts)
+
+	if err = result.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, "", false, nil
+		}
+		return 0, "", false, err
+	}
+
+	var bsonResource map[string]interface{}
+	if err = result.Decode(&bsonResource); err != nil {
+		return 0, "", false, err
+	}
+
+	revision, _ = bsonResource[contentRevisionName].(int64)
+	hash, _ = bsonResource[contentHashName].(string)
+	return revision, hash, true, nil
 }
 
 func (ma *MongoConnection) Read(collection string, uuidString string) (res *mapper.Resource, found bool, err error) {
@@ -156,6 +432,75 @@ func (ma *MongoConnection) Read(collection string, uuidString string) (res *mapp
 	if err = result.Decode(&bsonResource); err != nil {
 		return res, false, err
 	}
+	if _, err = ma.loadContent(collection, bsonResource); err != nil {
+		return res, false, err
+	}
+
+	res = ma.mapBsonToResource(bsonResource)
+	return res, true, nil
+}
+
+// ReadMetadata reads the latest revision of a document without fetching or decoding its content
+func (ma *MongoConnection) ReadMetadata(collection string, uuidString string) (res *mapper.Resource, found bool, err error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
+	defer cancel()
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
+	opts := options.FindOne().
+		SetSort(bsonx.Doc{
+			{Key: "content-revision", Value: bsonx.Int32(-1)},
+		}).
+		SetProjection(bson.M{contentName: 0, contentNumbersName: 0})
+	result := coll.FindOne(ctx, bson.M{uuidName: bsonUUID}, opts)
+
+	if err = result.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return res, false, nil
+		}
+		return res, false, err
+	}
+
+	var bsonResource map[string]interface{}
+	if err = result.Decode(&bsonResource); err != nil {
+		return res, false, err
+	}
+
+	res = ma.mapBsonToResource(bsonResource)
+	return res, true, nil
+}
+
+// ReadAsOf reads the newest revision of a document created at or before the given instant, in nanoseconds since the epoch
+func (ma *MongoConnection) ReadAsOf(collection string, uuidString string, asOf int64) (res *mapper.Resource, found bool, err error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
+	defer cancel()
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
+	opts := options.FindOne().
+		SetSort(bsonx.Doc{
+			{Key: "content-revision", Value: bsonx.Int32(-1)},
+		})
+	result := coll.FindOne(ctx,
+		bson.M{
+			uuidName:            bsonUUID,
+			contentRevisionName: bson.M{"$lte": asOf}},
+		opts)
+
+	if err = result.Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return res, false, nil
+		}
+		return res, false, err
+	}
+
+	var bsonResource map[string]interface{}
+	if err = result.Decode(&bsonResource); err != nil {
+		return res, false, err
+	}
+	if _, err = ma.loadContent(collection, bsonResource); err != nil {
+		return res, false, err
+	}
 
 	res = ma.mapBsonToResource(bsonResource)
 	return res, true, nil
@@ -182,6 +527,9 @@ func (ma *MongoConnection) ReadSingleRevision(collection string, uuidString stri
 	if err = result.Decode(&bsonResource); err != nil {
 		return res, err
 	}
+	if _, err = ma.loadContent(collection, bsonResource); err != nil {
+		return res, err
+	}
 
 	res = ma.mapBsonToResource(bsonResource)
 	return res, nil
@@ -192,7 +540,7 @@ func (ma *MongoConnection) mapBsonToResource(bsonResource map[string]interface{}
 
 	res := &mapper.Resource{
 		UUID:        uuid.UUID(uuidData).String(),
-		Content:     bsonResource["content"],
+		Content:     contentFromBSON(bsonResource[contentName], bsonResource[contentNumbersName]),
 		ContentType: bsonResource["content-type"].(string),
 	}
 
@@ -211,16 +559,33 @@ func (ma *MongoConnection) mapBsonToResource(bsonResource map[string]interface{}
 		res.ContentRevision = contentRevision.(int64)
 	}
 
+	res.Deleted, _ = bsonResource[deletedName].(bool)
+	res.ContentHash, _ = bsonResource[contentHashName].(string)
+	res.CanonicalHash, _ = bsonResource[canonicalHashName].(string)
+	res.ContentSize, _ = bsonResource[contentSizeName].(int64)
+
 	return res
 }
 
+// contentFromBSON returns raw content as the bytes stored in a BSON binary, and JSON content with its numbers as they were written
+func contentFromBSON(content interface{}, numbers interface{}) interface{} {
+	if binary, ok := content.(primitive.Binary); ok {
+		return binary.Data
+	}
+	return withNumberLiterals(fromBSONNumbers(content), numbers)
+}
+
 func (ma *MongoConnection) ReadRevisions(collection string, uuidString string) (res []int64, err error) {
 	coll := ma.client.Database(ma.dbName).Collection(collection)
 	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
 	defer cancel()
 
 	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
-	opts := options.Find().SetProjection(bson.M{"content-revision": 1})
+	opts := options.Find().
+		SetProjection(bson.M{"content-revision": 1}).
+		SetSort(bsonx.Doc{
+			{Key: "content-revision", Value: bsonx.Int32(1)},
+		})
 	cur, err := coll.Find(ctx, bson.M{uuidName: bsonUUID}, opts)
 
 	if err != nil {
@@ -248,6 +613,142 @@ func (ma *MongoConnection) ReadRevisions(collection string, uuidString string) (
 	return res, nil
 }
 
+// ReadRevisionsMetadata reads every revision of a document in ascending order, without fetching or decoding their content
+func (ma *MongoConnection) ReadRevisionsMetadata(ctx context.Context, collection string, uuidString string) (res []*mapper.Resource, err error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
+	opts := options.Find().
+		SetProjection(bson.M{contentName: 0, contentNumbersName: 0}).
+		SetSort(bsonx.Doc{
+			{Key: contentRevisionName, Value: bsonx.Int32(1)},
+		})
+	cur, err := coll.Find(ctx, bson.M{uuidName: bsonUUID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	res = []*mapper.Resource{}
+	for cur.Next(ctx) {
+		var bsonResource map[string]interface{}
+		if err = cur.Decode(&bsonResource); err != nil {
+			return nil, err
+		}
+		res = append(res, ma.mapBsonToResource(bsonResource))
+	}
+
+	return res, cur.Err()
+}
+
+// StoredRevision is a revision of a document together with the size of its BSON document in the store, including the GridFS file of a large content
+type StoredRevision struct {
+	*mapper.Resource
+	Size int64
+}
+
+// ReadStoredRevisions reads every revision of a document in ascending order, with its content and stored size
+func (ma *MongoConnection) ReadStoredRevisions(ctx context.Context, collection string, uuidString string) (res []StoredRevision, err error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
+	opts := options.Find().
+		SetSort(bsonx.Doc{
+			{Key: contentRevisionName, Value: bsonx.Int32(1)},
+		})
+	cur, err := coll.Find(ctx, bson.M{uuidName: bsonUUID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	res = []StoredRevision{}
+	for cur.Next(ctx) {
+		var bsonResource map[string]interface{}
+		if err = cur.Decode(&bsonResource); err != nil {
+			return nil, err
+		}
+		fileSize, err := ma.loadContent(collection, bsonResource)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, StoredRevision{Resource: ma.mapBsonToResource(bsonResource), Size: int64(len(cur.Current)) + fileSize})
+	}
+
+	return res, cur.Err()
+}
+
+// RevisionPage selects a page of revisions of a document, newest first.
+// Before and After are exclusive cursors, and when After is set the page holds the revisions immediately following it.
+type RevisionPage struct {
+	Limit  int64
+	Before int64
+	After  int64
+}
+
+// ReadRevisionsPage reads a page of revisions of a document sorted in descending order, without their content but with its stored hashes and size.
+// more reports whether there are further revisions past the page, older ones unless the page was selected with After.
+func (ma *MongoConnection) ReadRevisionsPage(collection string, uuidString string, page RevisionPage) (res []*mapper.Resource, more bool, err error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
+	defer cancel()
+
+	bsonUUID := bsonx.Binary(0x04, uuid.Parse(uuidString))
+	filter := bson.M{uuidName: bsonUUID}
+	revisionRange := bson.M{}
+	if page.Before > 0 {
+		revisionRange["$lt"] = page.Before
+	}
+	if page.After > 0 {
+		revisionRange["$gt"] = page.After
+	}
+	if len(revisionRange) > 0 {
+		filter[contentRevisionName] = revisionRange
+	}
+
+	// the revisions following After are the oldest ones in range, so they are fetched in ascending order and reversed
+	order := int32(-1)
+	if page.After > 0 {
+		order = 1
+	}
+	opts := options.Find().
+		SetSort(bsonx.Doc{
+			{Key: contentRevisionName, Value: bsonx.Int32(order)},
+		}).
+		SetLimit(page.Limit + 1).
+		SetProjection(bson.M{contentName: 0, contentFileName: 0, contentNumbersName: 0})
+
+	cur, err := coll.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cur.Close(ctx)
+
+	res = []*mapper.Resource{}
+	for cur.Next(ctx) {
+		var bsonResource map[string]interface{}
+		if err = cur.Decode(&bsonResource); err != nil {
+			return nil, false, err
+		}
+		res = append(res, ma.mapBsonToResource(bsonResource))
+	}
+	if err = cur.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if int64(len(res)) > page.Limit {
+		res = res[:page.Limit]
+		more = true
+	}
+	if order > 0 {
+		for i, j := 0, len(res)-1; i < j; i, j = i+1, j-1 {
+			res[i], res[j] = res[j], res[i]
+		}
+	}
+
+	return res, more, nil
+}
+
 func (ma *MongoConnection) Count(collection string, uuidString string, contentRevision int64) (count int64, err error) {
 	coll := ma.client.Database(ma.dbName).Collection(collection)
 	ctx, cancel := context.WithTimeout(context.Background(), mongoDefaultOperationTimeout)
@@ -262,11 +763,18 @@ func (ma *MongoConnection) Count(collection string, uuidString string, contentRe
 	return n, nil
 }
 
-func (ma *MongoConnection) ReadIDs(ctx context.Context, collection string) (chan string, error) {
+// ReadIDs streams the uuid of every document in the collection once.
+// Documents whose latest revision is a deletion are left out, unless includeDeleted is set.
+func (ma *MongoConnection) ReadIDs(ctx context.Context, collection string, includeDeleted bool) (chan string, error) {
 	coll := ma.client.Database(ma.dbName).Collection(collection)
 
+	// following the uuid-revision-index keeps the revisions of a document together, with the latest one last
 	opts := options.Find().
-		SetProjection(bson.M{uuidName: true}).
+		SetProjection(bson.M{uuidName: true, deletedName: true}).
+		SetSort(bsonx.Doc{
+			{Key: uuidName, Value: bsonx.Int32(1)},
+			{Key: contentRevisionName, Value: bsonx.Int32(1)},
+		}).
 		SetBatchSize(32)
 	cur, err := coll.Find(ctx, bson.M{}, opts)
 
@@ -279,16 +787,34 @@ func (ma *MongoConnection) ReadIDs(ctx context.Context, collection string) (chan
 		defer cur.Close(ctx)
 		defer close(ids)
 
-		var result map[string]interface{}
+		var current string
+		var deleted bool
+		emit := func() {
+			if current != "" && (includeDeleted || !deleted) {
+				ids <- current
+			}
+		}
+
 		for cur.Next(ctx) {
 			if ctx.Err() != nil {
 				//canceling the context doesn't cancel the `cur.Next()` until the batch fetch is exhausted
-				break
+				return
 			}
+			var result map[string]interface{}
 			if err := cur.Decode(&result); err != nil {
-				break
+				return
 			}
-			ids <- uuid.UUID(result["uuid"].(primitive.Binary).Data).String()
+
+			id := uuid.UUID(result["uuid"].(primitive.Binary).Data).String()
+			if id != current {
+				emit()
+				current = id
+			}
+			deleted, _ = result[deletedName].(bool)
+		}
+
+		if cur.Err() == nil {
+			emit()
 		}
 	}()
 
@@ -2,12 +2,16 @@ package db
 
 import (
 	"context"
+	"encoding/json"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/pborman/uuid"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/Financial-Times/go-logger"
 	"github.com/Financial-Times/nativerw/pkg/mapper"
@@ -54,6 +58,389 @@ func TestReadWriteDelete(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestReadWriteKeepsNumbers(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	expectedResource := generateResource()
+	expectedResource.Content = map[string]interface{}{
+		"id":    json.Number("9007199254740993"),
+		"price": json.Number("3.141592653589793238462643383279"),
+		"ratio": json.Number("0.25"),
+		"list":  []interface{}{json.Number("1.50"), "text", json.Number("1e2"), json.Number("0.1e1"), json.Number("1E400")},
+	}
+	err = connection.Write("universal-content", expectedResource)
+	assert.NoError(t, err)
+
+	res, found, err := connection.Read("universal-content", expectedResource.UUID)
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResource.Content, res.Content)
+
+	err = connection.Delete("universal-content", expectedResource.UUID, expectedResource.ContentRevision)
+	assert.NoError(t, err)
+}
+
+func TestReadWriteBinary(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	expectedResource := generateResource()
+	expectedResource.ContentType = "application/octet-stream"
+	expectedResource.Content = []byte{0x00, 0x01, 0xfe, 0xff}
+	err = connection.Write("universal-content", expectedResource)
+	assert.NoError(t, err)
+
+	res, found, err := connection.Read("universal-content", expectedResource.UUID)
+	assert.True(t, found)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedResource.Content, res.Content)
+	assert.Equal(t, expectedResource.ContentType, res.ContentType)
+
+	err = connection.Delete("universal-content", expectedResource.UUID, expectedResource.ContentRevision)
+	assert.NoError(t, err)
+}
+
+func TestDeleteAll(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+	skipWithoutReplicaSet(t, connection)
+
+	first := generateResource()
+	for i := int64(0); i < 3; i++ {
+		resource := generateResource()
+		resource.UUID = first.UUID
+		resource.ContentRevision = first.ContentRevision + i
+		err = connection.Write("universal-content", resource)
+		assert.NoError(t, err)
+	}
+
+	audit := AuditEntry{Action: "purge", Collection: "universal-content", UUID: first.UUID, RequestedBy: "legal@example.com", Time: time.Now()}
+	revisions, err := connection.DeleteAll("universal-content", first.UUID, audit)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{123, 124, 125}, revisions)
+
+	_, found, err := connection.Read("universal-content", first.UUID)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	auditEntries := connection.(*MongoConnection).client.Database("native-store").Collection(auditCollection)
+	var recorded map[string]interface{}
+	err = auditEntries.FindOne(context.Background(), bson.M{"uuid": first.UUID}).Decode(&recorded)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, recorded["revisions"])
+	assert.Equal(t, "legal@example.com", recorded["requested-by"])
+
+	revisions, err = connection.DeleteAll("universal-content", first.UUID, audit)
+	assert.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	n, err := auditEntries.CountDocuments(context.Background(), bson.M{"uuid": first.UUID})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, n, "purging a missing document is not audited")
+}
+
+func TestReadMetadata(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	expectedResource := generateResource()
+	err = connection.Write("universal-content", expectedResource)
+	assert.NoError(t, err)
+
+	res, found, err := connection.ReadMetadata("universal-content", expectedResource.UUID)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Nil(t, res.Content)
+	assert.Equal(t, expectedResource.ContentType, res.ContentType)
+	assert.Equal(t, expectedResource.SchemaVersion, res.SchemaVersion)
+	assert.Equal(t, expectedResource.ContentRevision, res.ContentRevision)
+}
+
+func TestReadAsOf(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	first := generateResource()
+	err = connection.Write("universal-content", first)
+	assert.NoError(t, err)
+
+	second := generateResource()
+	second.UUID = first.UUID
+	second.ContentRevision = first.ContentRevision + 10
+	err = connection.Write("universal-content", second)
+	assert.NoError(t, err)
+
+	res, found, err := connection.ReadAsOf("universal-content", first.UUID, second.ContentRevision-1)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, first.ContentRevision, res.ContentRevision)
+	assert.Equal(t, first.Content, res.Content)
+
+	res, found, err = connection.ReadAsOf("universal-content", first.UUID, second.ContentRevision)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, second.ContentRevision, res.ContentRevision)
+
+	_, found, err = connection.ReadAsOf("universal-content", first.UUID, first.ContentRevision-1)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestReadRevisionsPage(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	first := generateResource()
+	for i := int64(0); i < 5; i++ {
+		resource := generateResource()
+		resource.UUID = first.UUID
+		resource.ContentRevision = first.ContentRevision + i
+		err = connection.Write("universal-content", resource)
+		assert.NoError(t, err)
+	}
+
+	revisionsOf := func(resources []*mapper.Resource) []int64 {
+		revisions := []int64{}
+		for _, resource := range resources {
+			revisions = append(revisions, resource.ContentRevision)
+		}
+		return revisions
+	}
+
+	res, more, err := connection.ReadRevisionsPage("universal-content", first.UUID, RevisionPage{Limit: 2})
+	assert.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, []int64{127, 126}, revisionsOf(res))
+	assert.Nil(t, res[0].Content)
+
+	res, more, err = connection.ReadRevisionsPage("universal-content", first.UUID, RevisionPage{Limit: 2, Before: 124})
+	assert.NoError(t, err)
+	assert.False(t, more)
+	assert.Equal(t, []int64{123}, revisionsOf(res))
+
+	res, more, err = connection.ReadRevisionsPage("universal-content", first.UUID, RevisionPage{Limit: 2, After: 123})
+	assert.NoError(t, err)
+	assert.True(t, more)
+	assert.Equal(t, []int64{125, 124}, revisionsOf(res))
+
+	revisions, err := connection.ReadRevisions("universal-content", first.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{123, 124, 125, 126, 127}, revisions)
+}
+
+func TestDeleteRevisions(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	first := generateResource()
+	for i := int64(0); i < 3; i++ {
+		resource := generateResource()
+		resource.UUID = first.UUID
+		resource.ContentRevision = first.ContentRevision + i
+		err = connection.Write("universal-content", resource)
+		assert.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res, err := connection.ReadRevisionsMetadata(ctx, "universal-content", first.UUID)
+	assert.NoError(t, err)
+	assert.Len(t, res, 3)
+	for i, resource := range res {
+		assert.Nil(t, resource.Content)
+		assert.Equal(t, first.ContentRevision+int64(i), resource.ContentRevision)
+		assert.Equal(t, first.SchemaVersion, resource.SchemaVersion)
+	}
+
+	deleted, err := connection.DeleteRevisions(ctx, "universal-content", first.UUID, []int64{123, 124, 999})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	revisions, err := connection.ReadRevisions("universal-content", first.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{125}, revisions)
+}
+
+func TestReadStoredRevisions(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	first := generateResource()
+	err = connection.Write("universal-content", first)
+	assert.NoError(t, err)
+
+	second := generateResource()
+	second.UUID = first.UUID
+	second.ContentRevision = first.ContentRevision + 1
+	err = connection.Write("universal-content", second)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res, err := connection.ReadStoredRevisions(ctx, "universal-content", first.UUID)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.Equal(t, first.Content, res[0].Content)
+	assert.Equal(t, second.ContentRevision, res[1].ContentRevision)
+	assert.Greater(t, res[0].Size, int64(0))
+}
+
+func TestContentHash(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	hashed := generateResource()
+	hashed.ContentHash = "stored-hash"
+	err = connection.Write("universal-content", hashed)
+	assert.NoError(t, err)
+
+	res, found, err := connection.ReadMetadata("universal-content", hashed.UUID)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "stored-hash", res.ContentHash)
+
+	legacy := generateResource()
+	err = connection.Write("universal-content", legacy)
+	assert.NoError(t, err)
+
+	updated, err := connection.BackfillHashes(context.Background(), "universal-content", func(resource *mapper.Resource) error {
+		if resource.ContentHash == "" {
+			resource.ContentHash = "backfilled-hash"
+		}
+		resource.CanonicalHash = "backfilled-canonical-hash"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, updated, int64(1))
+
+	res, _, err = connection.ReadMetadata("universal-content", legacy.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, "backfilled-hash", res.ContentHash)
+	assert.Equal(t, "backfilled-canonical-hash", res.CanonicalHash)
+
+	res, _, err = connection.ReadMetadata("universal-content", hashed.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, "stored-hash", res.ContentHash)
+	assert.Equal(t, "backfilled-canonical-hash", res.CanonicalHash)
+}
+
+func TestWriteConditionally(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	first := generateResource()
+	err = connection.WriteConditionally("universal-content", first, Precondition{IfNoneMatch: &RevisionCondition{Any: true}})
+	assert.NoError(t, err)
+
+	second := generateResource()
+	second.UUID = first.UUID
+	second.ContentRevision = first.ContentRevision + 1
+	err = connection.WriteConditionally("universal-content", second, IfLatest(first.ContentRevision))
+	assert.NoError(t, err)
+
+	stale := generateResource()
+	stale.UUID = first.UUID
+	stale.ContentRevision = first.ContentRevision + 2
+	err = connection.WriteConditionally("universal-content", stale, IfLatest(first.ContentRevision))
+	assert.ErrorIs(t, err, ErrPreconditionFailed)
+
+	res, found, err := connection.Read("universal-content", first.UUID)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, second.ContentRevision, res.ContentRevision)
+	assert.Equal(t, second.Content, res.Content)
+}
+
+func TestConcurrentWriteConditionally(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+	connection.EnsureIndex()
+
+	first := generateResource()
+	err = connection.Write("universal-content", first)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			next := generateResource()
+			next.UUID = first.UUID
+			next.ContentRevision = first.ContentRevision + int64(i) + 1
+			errs[i] = connection.WriteConditionally("universal-content", next, IfLatest(first.ContentRevision))
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, err, ErrPreconditionFailed)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestConcurrentWriteAndWriteConditionally(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+	connection.EnsureIndex()
+	coll := connection.(*MongoConnection).client.Database("native-store").Collection("universal-content")
+
+	for i := 0; i < 20; i++ {
+		first := generateResource()
+		err = connection.Write("universal-content", first)
+		assert.NoError(t, err)
+
+		conditional := generateResource()
+		conditional.UUID = first.UUID
+		conditional.ContentRevision = first.ContentRevision + 1
+		unconditional := generateResource()
+		unconditional.UUID = first.UUID
+		unconditional.ContentRevision = first.ContentRevision + 2
+
+		var wg sync.WaitGroup
+		var conditionalErr, unconditionalErr error
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			conditionalErr = connection.WriteConditionally("universal-content", conditional, IfLatest(first.ContentRevision))
+		}()
+		go func() {
+			defer wg.Done()
+			unconditionalErr = connection.Write("universal-content", unconditional)
+		}()
+		wg.Wait()
+		assert.NoError(t, unconditionalErr)
+
+		var stored struct {
+			Supersedes struct {
+				ContentRevision int64 `bson:"content-revision"`
+			} `bson:"supersedes"`
+		}
+		err = coll.FindOne(context.Background(), bson.M{
+			"uuid":             bsonx.Binary(0x04, uuid.Parse(first.UUID)),
+			"content-revision": unconditional.ContentRevision,
+		}).Decode(&stored)
+		assert.NoError(t, err)
+
+		// either the conditional write lost, or the unconditional one was written on top of it: never both on top of the first revision
+		if conditionalErr == nil {
+			assert.Equal(t, conditional.ContentRevision, stored.Supersedes.ContentRevision)
+		} else {
+			assert.ErrorIs(t, conditionalErr, ErrPreconditionFailed)
+			assert.Equal(t, first.ContentRevision, stored.Supersedes.ContentRevision)
+		}
+	}
+}
+
 func TestGetSupportedCollections(t *testing.T) {
 	connection, err := startMongo(t)
 	assert.NoError(t, err)
@@ -88,9 +475,15 @@ func TestEnsureIndexes(t *testing.T) {
 			assert.Equal(t, primitive.M{"uuid": int32(1), "content-revision": int32(1)}, index["key"])
 			count = count + 1
 		}
+		if index["name"] == "supersedes-index" {
+			assert.True(t, index["unique"].(bool))
+			assert.True(t, index["sparse"].(bool))
+			assert.Equal(t, primitive.M{"supersedes": int32(1)}, index["key"])
+			count = count + 1
+		}
 	}
 
-	assert.Equal(t, 1, count)
+	assert.Equal(t, 2, count)
 }
 
 func TestReadIDs(t *testing.T) {
@@ -105,7 +498,7 @@ func TestReadIDs(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	ids, err := connection.ReadIDs(ctx, "universal-content")
+	ids, err := connection.ReadIDs(ctx, "universal-content", false)
 
 	assert.NoError(t, err)
 	found := false
@@ -119,6 +512,45 @@ func TestReadIDs(t *testing.T) {
 	assert.True(t, found)
 }
 
+func TestReadIDsWithTombstones(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+
+	deleted := generateResource()
+	err = connection.Write("universal-content", deleted)
+	assert.NoError(t, err)
+
+	tombstone := mapper.Tombstone(deleted.UUID, "application/json", "", "14", deleted.ContentRevision+1)
+	err = connection.Write("universal-content", tombstone)
+	assert.NoError(t, err)
+
+	res, found, err := connection.Read("universal-content", deleted.UUID)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.True(t, res.Deleted)
+
+	res, err = connection.ReadSingleRevision("universal-content", deleted.UUID, deleted.ContentRevision)
+	assert.NoError(t, err)
+	assert.False(t, res.Deleted)
+
+	readIDs := func(includeDeleted bool) map[string]int {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ids, err := connection.ReadIDs(ctx, "universal-content", includeDeleted)
+		assert.NoError(t, err)
+
+		counts := map[string]int{}
+		for id := range ids {
+			counts[id]++
+		}
+		return counts
+	}
+
+	assert.NotContains(t, readIDs(false), deleted.UUID)
+	assert.Equal(t, 1, readIDs(true)[deleted.UUID])
+}
+
 func TestReadMoreThanOneBatch(t *testing.T) {
 	connection, err := startMongo(t)
 
@@ -134,7 +566,7 @@ func TestReadMoreThanOneBatch(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	ids, err := connection.ReadIDs(ctx, "universal-content")
+	ids, err := connection.ReadIDs(ctx, "universal-content", false)
 
 	assert.NoError(t, err)
 	count := 0
@@ -161,7 +593,7 @@ func TestCancelReadIDs(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // just in case
 
-	ids, err := connection.ReadIDs(ctx, "universal-content")
+	ids, err := connection.ReadIDs(ctx, "universal-content", false)
 
 	assert.NoError(t, err)
 
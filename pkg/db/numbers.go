@@ -0,0 +1,194 @@
+package db
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toBSONNumbers returns a copy of a content with its JSON numbers converted to the BSON type storing them exactly:
+// integers fitting in 64 bits are stored as int64, numbers read back identically from a double as doubles, and the others as decimal128.
+// The few numbers fitting none of them, with more than 34 significant digits, are stored as the nearest double.
+// The numbers which their BSON value does not write back as received are also kept as literals, see numberLiterals.
+func toBSONNumbers(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for k, child := range node {
+			c[k] = toBSONNumbers(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, child := range node {
+			c[i] = toBSONNumbers(child)
+		}
+		return c
+	case primitive.A:
+		return toBSONNumbers([]interface{}(node))
+	case json.Number:
+		return bsonNumber(node)
+	}
+	return v
+}
+
+const (
+	numberPointerName = "pointer"
+	numberLiteralName = "literal"
+)
+
+var (
+	pointerEscaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+func bsonNumber(n json.Number) interface{} {
+	s := n.String()
+	if !strings.ContainsAny(s, ".eE") {
+		// -0 is kept as a double, which has a negative zero
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil && (i != 0 || s == "0") {
+			return i
+		}
+	}
+
+	f, err := n.Float64()
+	if err == nil && jsonFloat(f) == s {
+		return f
+	}
+
+	if d, err := primitive.ParseDecimal128(s); err == nil {
+		return d
+	}
+	return f
+}
+
+// fromBSONNumbers returns a copy of a stored content with its numbers converted back to JSON numbers, written as they were received
+func fromBSONNumbers(v interface{}) interface{} {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for k, child := range node {
+			c[k] = fromBSONNumbers(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, child := range node {
+			c[i] = fromBSONNumbers(child)
+		}
+		return c
+	case primitive.A:
+		return fromBSONNumbers([]interface{}(node))
+	case int32:
+		return json.Number(strconv.FormatInt(int64(node), 10))
+	case int64:
+		return json.Number(strconv.FormatInt(node, 10))
+	case float64:
+		if math.IsNaN(node) || math.IsInf(node, 0) {
+			return node
+		}
+		return json.Number(jsonFloat(node))
+	case primitive.Decimal128:
+		return json.Number(node.String())
+	}
+	return v
+}
+
+// numberLiterals returns the numbers of a content which their BSON value does not write back as they were received,
+// e.g. 1e2 stored as the decimal128 1E+2, as documents holding their JSON pointer and their literal, sorted by pointer
+func numberLiterals(v interface{}) []interface{} {
+	literals := map[string]string{}
+	collectNumberLiterals(v, "", literals)
+	if len(literals) == 0 {
+		return nil
+	}
+
+	pointers := make([]string, 0, len(literals))
+	for pointer := range literals {
+		pointers = append(pointers, pointer)
+	}
+	sort.Strings(pointers)
+
+	res := make([]interface{}, len(pointers))
+	for i, pointer := range pointers {
+		res[i] = map[string]interface{}{numberPointerName: pointer, numberLiteralName: literals[pointer]}
+	}
+	return res
+}
+
+func collectNumberLiterals(v interface{}, pointer string, literals map[string]string) {
+	switch node := v.(type) {
+	case map[string]interface{}:
+		for k, child := range node {
+			collectNumberLiterals(child, pointer+"/"+pointerEscaper.Replace(k), literals)
+		}
+	case []interface{}:
+		for i, child := range node {
+			collectNumberLiterals(child, pointer+"/"+strconv.Itoa(i), literals)
+		}
+	case json.Number:
+		if fromBSONNumbers(bsonNumber(node)) != node {
+			literals[pointer] = node.String()
+		}
+	}
+}
+
+// withNumberLiterals sets the numbers recorded by numberLiterals back to their literals in a content read from the store
+func withNumberLiterals(content interface{}, literals interface{}) interface{} {
+	stored, _ := literals.(primitive.A)
+	for _, l := range stored {
+		literal, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pointer, _ := literal[numberPointerName].(string)
+		value, _ := literal[numberLiteralName].(string)
+		setNumber(content, pointer, json.Number(value))
+	}
+	return content
+}
+
+// setNumber replaces the number at a JSON pointer of a content, and leaves the content unchanged if the pointer does not lead to a number
+func setNumber(content interface{}, pointer string, n json.Number) {
+	tokens := strings.Split(pointer, "/")
+	if len(tokens) < 2 || tokens[0] != "" {
+		return
+	}
+
+	node := content
+	for i, token := range tokens[1:] {
+		last := i == len(tokens)-2
+		switch container := node.(type) {
+		case map[string]interface{}:
+			key := pointerUnescaper.Replace(token)
+			if _, isNumber := container[key].(json.Number); last && isNumber {
+				container[key] = n
+			}
+			node = container[key]
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(container) {
+				return
+			}
+			if _, isNumber := container[index].(json.Number); last && isNumber {
+				container[index] = n
+			}
+			node = container[index]
+		default:
+			return
+		}
+	}
+}
+
+// jsonFloat formats a double the way encoding/json does, so that the contents stored before numbers were kept exactly are served unchanged
+func jsonFloat(f float64) string {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestToBSONNumbers(t *testing.T) {
+	content := map[string]interface{}{
+		"id":       json.Number("9007199254740993"),
+		"count":    json.Number("2"),
+		"ratio":    json.Number("0.25"),
+		"price":    json.Number("3.141592653589793238462643383279"),
+		"exponent": json.Number("1e2"),
+		"big":      json.Number("123456789012345678901234"),
+		"zero":     json.Number("-0"),
+		"nested":   map[string]interface{}{"values": []interface{}{json.Number("1.50"), "text"}},
+	}
+
+	stored := toBSONNumbers(content).(map[string]interface{})
+
+	price, _ := primitive.ParseDecimal128("3.141592653589793238462643383279")
+	exponent, _ := primitive.ParseDecimal128("1e2")
+	big, _ := primitive.ParseDecimal128("123456789012345678901234")
+	ratio, _ := primitive.ParseDecimal128("1.50")
+	assert.Equal(t, int64(9007199254740993), stored["id"])
+	assert.Equal(t, int64(2), stored["count"])
+	assert.Equal(t, 0.25, stored["ratio"])
+	assert.Equal(t, price, stored["price"])
+	assert.Equal(t, exponent, stored["exponent"])
+	assert.Equal(t, big, stored["big"])
+	assert.IsType(t, float64(0), stored["zero"])
+	assert.Equal(t, []interface{}{ratio, "text"}, stored["nested"].(map[string]interface{})["values"])
+	assert.Equal(t, json.Number("9007199254740993"), content["id"], "the original content should not be modified")
+}
+
+func TestBSONNumbersRoundTrip(t *testing.T) {
+	payload := `{"id":9007199254740993,"count":2,"ratio":0.25,"price":3.141592653589793238462643383279,"decimal":1.50,"big":123456789012345678901234,"zero":-0,"nested":{"values":[1.50,"text",1e-7]}}`
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	var content map[string]interface{}
+	assert.NoError(t, decoder.Decode(&content))
+
+	data, err := bson.Marshal(map[string]interface{}{"content": toBSONNumbers(content)})
+	assert.NoError(t, err)
+
+	var stored map[string]interface{}
+	assert.NoError(t, bson.Unmarshal(data, &stored))
+
+	read, err := json.Marshal(fromBSONNumbers(stored["content"]))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"big":123456789012345678901234,"count":2,"decimal":1.50,"id":9007199254740993,"nested":{"values":[1.50,"text",1e-7]},"price":3.141592653589793238462643383279,"ratio":0.25,"zero":-0}`, string(read))
+}
+
+func TestFromBSONNumbersOfDoubles(t *testing.T) {
+	content := fromBSONNumbers(map[string]interface{}{"count": float64(2), "ratio": 0.1, "large": 1e21, "small": int32(7)})
+
+	read, err := json.Marshal(content)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":2,"large":1e+21,"ratio":0.1,"small":7}`, string(read))
+}
+
+func TestNumberLiteralsRoundTrip(t *testing.T) {
+	payload := `{"exponent":1e2,"fraction":0.1e1,"huge":1E400,"digits":1.2345678901234567890123456789012345678,"plain":2.5,"a/b":{"c~d":[1,1E3]}}`
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	decoder.UseNumber()
+	var content map[string]interface{}
+	assert.NoError(t, decoder.Decode(&content))
+
+	literals := numberLiterals(content)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"pointer": "/a~1b/c~0d/1", "literal": "1E3"},
+		map[string]interface{}{"pointer": "/digits", "literal": "1.2345678901234567890123456789012345678"},
+		map[string]interface{}{"pointer": "/exponent", "literal": "1e2"},
+		map[string]interface{}{"pointer": "/fraction", "literal": "0.1e1"},
+		map[string]interface{}{"pointer": "/huge", "literal": "1E400"},
+	}, literals)
+
+	data, err := bson.Marshal(map[string]interface{}{"content": toBSONNumbers(content), "content-numbers": literals})
+	assert.NoError(t, err)
+
+	var stored map[string]interface{}
+	assert.NoError(t, bson.Unmarshal(data, &stored))
+
+	read, err := json.Marshal(contentFromBSON(stored["content"], stored["content-numbers"]))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a/b":{"c~d":[1,1E3]},"digits":1.2345678901234567890123456789012345678,"exponent":1e2,"fraction":0.1e1,"huge":1E400,"plain":2.5}`, string(read))
+}
+
+func TestNumberLiteralsOfExactNumbers(t *testing.T) {
+	content := map[string]interface{}{"id": json.Number("9007199254740993"), "ratio": json.Number("0.25"), "decimal": json.Number("1.50"), "text": "1e2"}
+	assert.Nil(t, numberLiterals(content))
+}
+
+func TestWithNumberLiteralsIgnoresPointersToOtherValues(t *testing.T) {
+	content := map[string]interface{}{"title": "Title", "values": []interface{}{json.Number("1")}}
+	literals := primitive.A{
+		map[string]interface{}{"pointer": "/title", "literal": "1e2"},
+		map[string]interface{}{"pointer": "/values/3", "literal": "1e2"},
+		map[string]interface{}{"pointer": "/missing/value", "literal": "1e2"},
+	}
+
+	assert.Equal(t, map[string]interface{}{"title": "Title", "values": []interface{}{json.Number("1")}}, withNumberLiterals(content, literals))
+}
@@ -0,0 +1,56 @@
+package db
+
+import "errors"
+
+// ErrPreconditionFailed is returned by conditional writes when the latest stored revision does not satisfy the precondition
+var ErrPreconditionFailed = errors.New("precondition failed, the latest revision does not match")
+
+// RevisionCondition matches the latest revision of a document against a set of revisions and of content hashes
+type RevisionCondition struct {
+	Any       bool
+	Revisions []int64
+	Hashes    []string
+}
+
+func (c *RevisionCondition) matches(latest int64, latestHash string, found bool) bool {
+	if !found {
+		return false
+	}
+	if c.Any {
+		return true
+	}
+	for _, revision := range c.Revisions {
+		if revision == latest {
+			return true
+		}
+	}
+	for _, hash := range c.Hashes {
+		if latestHash != "" && hash == latestHash {
+			return true
+		}
+	}
+	return false
+}
+
+// Precondition describes the state the latest revision of a document must be in for a conditional write to proceed.
+// A nil condition is not checked.
+type Precondition struct {
+	IfMatch     *RevisionCondition
+	IfNoneMatch *RevisionCondition
+}
+
+// IfLatest creates a precondition which only holds while the given revision is the latest one
+func IfLatest(revision int64) Precondition {
+	return Precondition{IfMatch: &RevisionCondition{Revisions: []int64{revision}}}
+}
+
+// SatisfiedBy checks the precondition against the latest stored revision and its content hash, which is empty when it has none
+func (p Precondition) SatisfiedBy(latest int64, latestHash string, found bool) bool {
+	if p.IfMatch != nil && !p.IfMatch.matches(latest, latestHash, found) {
+		return false
+	}
+	if p.IfNoneMatch != nil && p.IfNoneMatch.matches(latest, latestHash, found) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreconditionSatisfiedBy(t *testing.T) {
+	var tests = []struct {
+		name         string
+		precondition Precondition
+		latest       int64
+		latestHash   string
+		found        bool
+		expected     bool
+	}{
+		{"no conditions", Precondition{}, 1, "", true, true},
+		{"no conditions and no document", Precondition{}, 0, "", false, true},
+		{"if-match latest", IfLatest(2), 2, "", true, true},
+		{"if-match older revision", IfLatest(1), 2, "", true, false},
+		{"if-match without document", IfLatest(1), 0, "", false, false},
+		{"if-match any", Precondition{IfMatch: &RevisionCondition{Any: true}}, 2, "", true, true},
+		{"if-match any without document", Precondition{IfMatch: &RevisionCondition{Any: true}}, 0, "", false, false},
+		{"if-none-match any without document", Precondition{IfNoneMatch: &RevisionCondition{Any: true}}, 0, "", false, true},
+		{"if-none-match any with document", Precondition{IfNoneMatch: &RevisionCondition{Any: true}}, 2, "", true, false},
+		{"if-none-match latest", Precondition{IfNoneMatch: &RevisionCondition{Revisions: []int64{2}}}, 2, "", true, false},
+		{"if-none-match older revision", Precondition{IfNoneMatch: &RevisionCondition{Revisions: []int64{1}}}, 2, "", true, true},
+		{"if-match latest hash", Precondition{IfMatch: &RevisionCondition{Hashes: []string{"hash"}}}, 2, "hash", true, true},
+		{"if-match other hash", Precondition{IfMatch: &RevisionCondition{Hashes: []string{"other"}}}, 2, "hash", true, false},
+		{"if-match hash of a revision without hash", Precondition{IfMatch: &RevisionCondition{Hashes: []string{"hash"}}}, 2, "", true, false},
+		{"if-none-match latest hash", Precondition{IfNoneMatch: &RevisionCondition{Hashes: []string{"hash"}}}, 2, "hash", true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, test.precondition.SatisfiedBy(test.latest, test.latestHash, test.found))
+		})
+	}
+}
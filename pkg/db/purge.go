@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+
+	"github.com/pborman/uuid"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// RevisionFilter selects revisions across documents. Unset criteria match every revision.
+// From and To bound the content revisions, From inclusive and To exclusive.
+type RevisionFilter struct {
+	From           int64
+	To             int64
+	OriginSystemID string
+	SchemaVersion  string
+	UUIDs          []string
+}
+
+// IsEmpty reports whether the filter matches every revision
+func (f RevisionFilter) IsEmpty() bool {
+	return f.From == 0 && f.To == 0 && f.OriginSystemID == "" && f.SchemaVersion == "" && len(f.UUIDs) == 0
+}
+
+func (f RevisionFilter) query() bson.M {
+	query := bson.M{}
+
+	revisionRange := bson.M{}
+	if f.From > 0 {
+		revisionRange["$gte"] = f.From
+	}
+	if f.To > 0 {
+		revisionRange["$lt"] = f.To
+	}
+	if len(revisionRange) > 0 {
+		query[contentRevisionName] = revisionRange
+	}
+
+	if f.OriginSystemID != "" {
+		query["origin-system-id"] = f.OriginSystemID
+	}
+	if f.SchemaVersion != "" {
+		query["schema-version"] = f.SchemaVersion
+	}
+
+	if len(f.UUIDs) > 0 {
+		uuids := make([]interface{}, len(f.UUIDs))
+		for i, uuidString := range f.UUIDs {
+			uuids[i] = bsonx.Binary(0x04, uuid.Parse(uuidString))
+		}
+		query[uuidName] = bson.M{"$in": uuids}
+	}
+
+	return query
+}
+
+// DeleteMatching deletes up to batchSize revisions matching the filter, and returns how many were deleted.
+// Callers repeat it until nothing is left to delete. Every batch is recorded with the given audit entry, carrying the number of revisions of the batch,
+// in the same transaction as its deletion, so that no erasure happens without its record. Nothing is audited when nothing matches anymore.
+func (ma *MongoConnection) DeleteMatching(ctx context.Context, collection string, filter RevisionFilter, batchSize int64, audit AuditEntry) (int64, error) {
+	coll := ma.client.Database(ma.dbName).Collection(collection)
+
+	session, err := ma.client.StartSession()
+	if err != nil {
+		return 0, err
+	}
+	defer session.EndSession(ctx)
+
+	var deleted int64
+	var files []interface{}
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		deleted, files = 0, nil
+
+		opts := options.Find().
+			SetProjection(bson.M{"_id": 1, contentFileName: 1}).
+			SetLimit(batchSize)
+		cur, err := coll.Find(sc, filter.query(), opts)
+		if err != nil {
+			return nil, err
+		}
+		defer cur.Close(sc)
+
+		var ids []interface{}
+		for cur.Next(sc) {
+			var result map[string]interface{}
+			if err := cur.Decode(&result); err != nil {
+				return nil, err
+			}
+			ids = append(ids, result["_id"])
+			if fileID, found := result[contentFileName]; found {
+				files = append(files, fileID)
+			}
+		}
+		if err := cur.Err(); err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, nil
+		}
+
+		result, err := coll.DeleteMany(sc, bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return nil, err
+		}
+		deleted = result.DeletedCount
+		audit.Revisions = int(deleted)
+		return nil, ma.insertAudit(sc, audit)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	ma.removeContentFiles(ctx, collection, files)
+	return deleted, nil
+}
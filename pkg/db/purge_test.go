@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestDeleteMatching(t *testing.T) {
+	connection, err := startMongo(t)
+	assert.NoError(t, err)
+	skipWithoutReplicaSet(t, connection)
+
+	matching := generateResource()
+	matching.OriginSystemID = "load-test"
+	for i := int64(0); i < 3; i++ {
+		resource := generateResource()
+		resource.UUID = matching.UUID
+		resource.OriginSystemID = matching.OriginSystemID
+		resource.ContentRevision = matching.ContentRevision + i
+		err = connection.Write("universal-content", resource)
+		assert.NoError(t, err)
+	}
+
+	other := generateResource()
+	other.OriginSystemID = "methode-web-pub"
+	err = connection.Write("universal-content", other)
+	assert.NoError(t, err)
+
+	filter := RevisionFilter{OriginSystemID: "load-test", UUIDs: []string{matching.UUID, other.UUID}, From: 124}
+	audit := AuditEntry{Action: "bulk-purge", Collection: "universal-content", Filter: `{"uuids":["` + matching.UUID + `"]}`, RequestedBy: "ops@example.com", Time: time.Now()}
+
+	deleted, err := connection.DeleteMatching(context.Background(), "universal-content", filter, 1, audit)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	deleted, err = connection.DeleteMatching(context.Background(), "universal-content", filter, 10, audit)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	deleted, err = connection.DeleteMatching(context.Background(), "universal-content", filter, 10, audit)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	auditEntries := connection.(*MongoConnection).client.Database("native-store").Collection(auditCollection)
+	cur, err := auditEntries.Find(context.Background(), bson.M{"filter": audit.Filter})
+	assert.NoError(t, err)
+	var recorded []map[string]interface{}
+	assert.NoError(t, cur.All(context.Background(), &recorded))
+	assert.Len(t, recorded, 2, "every batch is audited, except the last one which deletes nothing")
+	for _, entry := range recorded {
+		assert.EqualValues(t, 1, entry["revisions"])
+		assert.Equal(t, "ops@example.com", entry["requested-by"])
+	}
+
+	revisions, err := connection.ReadRevisions("universal-content", matching.UUID)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{123}, revisions)
+
+	_, found, err := connection.Read("universal-content", other.UUID)
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestRevisionFilterIsEmpty(t *testing.T) {
+	assert.True(t, RevisionFilter{}.IsEmpty())
+	assert.True(t, RevisionFilter{UUIDs: []string{}}.IsEmpty())
+	assert.False(t, RevisionFilter{To: 1}.IsEmpty())
+	assert.False(t, RevisionFilter{UUIDs: []string{"a0c2a8f2-1c3a-4e4b-8d5a-9e8b7c6d5e4f"}}.IsEmpty())
+}
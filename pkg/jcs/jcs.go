@@ -0,0 +1,175 @@
+// Package jcs serialises JSON values in the canonical form of RFC 8785, the JSON Canonicalization Scheme,
+// so that the same document always hashes to the same value whatever the key order and number formatting it was sent with.
+package jcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// ErrUnsupportedNumber is returned for the numbers which have no canonical serialisation, as they do not fit in a double, e.g. 1E400
+var ErrUnsupportedNumber = errors.New("jcs: unsupported number")
+
+// Marshal returns the canonical serialisation of a value. Values of types not produced by decoding JSON are converted through their JSON encoding first.
+func Marshal(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := encode(&b, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func encode(b *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case bool:
+		b.WriteString(strconv.FormatBool(value))
+	case string:
+		encodeString(b, value)
+	case float64:
+		return encodeNumber(b, value)
+	case json.Number:
+		f, err := value.Float64()
+		if err != nil {
+			return fmt.Errorf("%w %v", ErrUnsupportedNumber, value)
+		}
+		return encodeNumber(b, f)
+	case map[string]interface{}:
+		return encodeObject(b, value)
+	case []interface{}:
+		return encodeArray(b, value)
+	default:
+		return encodeJSON(b, value)
+	}
+	return nil
+}
+
+// encodeJSON canonicalises a value through its JSON encoding, keeping the numbers as written
+func encodeJSON(b *bytes.Buffer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+	return encode(b, decoded)
+}
+
+// encodeNumber writes a number as ECMAScript would convert it to a string
+func encodeNumber(b *bytes.Buffer, f float64) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("%w %v", ErrUnsupportedNumber, f)
+	}
+	if f == 0 {
+		// also turns -0 into 0
+		b.WriteString("0")
+		return nil
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+	s := strconv.FormatFloat(f, format, -1, 64)
+	if format == 'e' {
+		// the exponent has no leading zero, e.g. 1e-7 rather than 1e-07
+		if n := len(s); n >= 4 && s[n-4] == 'e' && s[n-2] == '0' {
+			s = s[:n-2] + s[n-1:]
+		}
+	}
+	b.WriteString(s)
+	return nil
+}
+
+// encodeString only escapes the quotation mark, the backslash and the control characters, using the short forms where there is one
+func encodeString(b *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				b.WriteString(`\u00`)
+				b.WriteByte(hex[r>>4])
+				b.WriteByte(hex[r&0xf])
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+}
+
+// encodeObject writes the members sorted by the UTF-16 code units of their names
+func encodeObject(b *bytes.Buffer, object map[string]interface{}) error {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return lessUTF16(names[i], names[j]) })
+
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		encodeString(b, name)
+		b.WriteByte(':')
+		if err := encode(b, object[name]); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func encodeArray(b *bytes.Buffer, array []interface{}) error {
+	b.WriteByte('[')
+	for i, element := range array {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := encode(b, element); err != nil {
+			return err
+		}
+	}
+	b.WriteByte(']')
+	return nil
+}
+
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,74 @@
+package jcs
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, data string) interface{} {
+	var v interface{}
+	require.NoError(t, json.Unmarshal([]byte(data), &v))
+	return v
+}
+
+func TestMarshal(t *testing.T) {
+	var tests = []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "RFC 8785 example",
+			input:    `{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001], "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/", "literals": [null, true, false]}`,
+			expected: "{\"literals\":[null,true,false],\"numbers\":[333333333.3333333,1e+30,4.5,0.002,1e-27],\"string\":\"\u20ac$\\u000f\\nA'B\\\"\\\\\\\\\\\"/\"}",
+		},
+		{
+			name:     "members sorted by UTF-16 code units",
+			input:    `{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh", "1": "One", "\ud83d\ude00": "Emoji: Grinning Face", "\u0080": "Control", "\u00f6": "Latin Small Letter O With Diaeresis"}`,
+			expected: "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			name:     "nested objects",
+			input:    `{"b": {"d": 1, "c": [{"f": 2, "e": 3}]}, "a": "<&>"}`,
+			expected: `{"a":"<&>","b":{"c":[{"e":3,"f":2}],"d":1}}`,
+		},
+		{
+			name:     "numbers",
+			input:    `[0, -0, 1e21, 1e20, 1e-7, 0.000001, -1.5, 100, 12345678901234567890]`,
+			expected: `[0,0,1e+21,100000000000000000000,1e-7,0.000001,-1.5,100,12345678901234567000]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, err := Marshal(decode(t, test.input))
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, string(actual))
+		})
+	}
+}
+
+func TestMarshalOtherTypes(t *testing.T) {
+	actual, err := Marshal(map[string]interface{}{
+		"int":    int64(42),
+		"number": json.Number("1.50"),
+		"struct": struct {
+			B string `json:"b"`
+			A int    `json:"a"`
+		}{"x", 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"int":42,"number":1.5,"struct":{"a":1,"b":"x"}}`, string(actual))
+}
+
+func TestMarshalInvalidNumber(t *testing.T) {
+	_, err := Marshal([]interface{}{math.Inf(1)})
+	assert.ErrorIs(t, err, ErrUnsupportedNumber)
+
+	_, err = Marshal(map[string]interface{}{"a": json.Number("1E400")})
+	assert.ErrorIs(t, err, ErrUnsupportedNumber)
+}
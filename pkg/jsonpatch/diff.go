@@ -0,0 +1,78 @@
+package jsonpatch
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Diff creates a patch which transforms the from document into the to document
+func Diff(from, to interface{}) Patch {
+	return diff(Pointer{}, deepCopy(from), deepCopy(to), Patch{})
+}
+
+func diff(path Pointer, from, to interface{}, patch Patch) Patch {
+	switch f := from.(type) {
+	case map[string]interface{}:
+		if t, ok := to.(map[string]interface{}); ok {
+			return diffObjects(path, f, t, patch)
+		}
+	case []interface{}:
+		if t, ok := to.([]interface{}); ok {
+			return diffArrays(path, f, t, patch)
+		}
+	}
+
+	if equal(from, to) {
+		return patch
+	}
+	return append(patch, Operation{Op: "replace", Path: path.String(), Value: to})
+}
+
+func diffObjects(path Pointer, from, to map[string]interface{}, patch Patch) Patch {
+	for _, key := range sortedKeys(from) {
+		if _, found := to[key]; !found {
+			patch = append(patch, Operation{Op: "remove", Path: child(path, key).String()})
+		}
+	}
+	for _, key := range sortedKeys(to) {
+		value, found := from[key]
+		if !found {
+			patch = append(patch, Operation{Op: "add", Path: child(path, key).String(), Value: to[key]})
+			continue
+		}
+		patch = diff(child(path, key), value, to[key], patch)
+	}
+	return patch
+}
+
+// diffArrays compares the elements both arrays have, then removes or appends the rest
+func diffArrays(path Pointer, from, to []interface{}, patch Patch) Patch {
+	common := len(from)
+	if len(to) < common {
+		common = len(to)
+	}
+
+	for i := 0; i < common; i++ {
+		patch = diff(child(path, strconv.Itoa(i)), from[i], to[i], patch)
+	}
+	for i := len(from) - 1; i >= common; i-- {
+		patch = append(patch, Operation{Op: "remove", Path: child(path, strconv.Itoa(i)).String()})
+	}
+	for i := common; i < len(to); i++ {
+		patch = append(patch, Operation{Op: "add", Path: child(path, "-").String(), Value: to[i]})
+	}
+	return patch
+}
+
+func child(path Pointer, token string) Pointer {
+	return append(path[:len(path):len(path)], token)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,79 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	var tests = []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "identical documents",
+			from:     `{"a": 1, "b": [1, 2]}`,
+			to:       `{"b": [1, 2], "a": 1.0}`,
+			expected: `[]`,
+		},
+		{
+			name:     "object members",
+			from:     `{"a": 1, "b": {"c": "d", "e": "f"}, "g": true}`,
+			to:       `{"a": 2, "b": {"c": "d", "x": "y"}, "h": null}`,
+			expected: `[{"op": "remove", "path": "/g"}, {"op": "replace", "path": "/a", "value": 2}, {"op": "remove", "path": "/b/e"}, {"op": "add", "path": "/b/x", "value": "y"}, {"op": "add", "path": "/h", "value": null}]`,
+		},
+		{
+			name:     "shorter array",
+			from:     `{"a": [1, 2, 3, 4]}`,
+			to:       `{"a": [1, 5]}`,
+			expected: `[{"op": "replace", "path": "/a/1", "value": 5}, {"op": "remove", "path": "/a/3"}, {"op": "remove", "path": "/a/2"}]`,
+		},
+		{
+			name:     "longer array",
+			from:     `{"a": [{"b": 1}]}`,
+			to:       `{"a": [{"b": 2}, "c"]}`,
+			expected: `[{"op": "replace", "path": "/a/0/b", "value": 2}, {"op": "add", "path": "/a/-", "value": "c"}]`,
+		},
+		{
+			name:     "type change",
+			from:     `{"a": {"b": 1}}`,
+			to:       `{"a": ["b"]}`,
+			expected: `[{"op": "replace", "path": "/a", "value": ["b"]}]`,
+		},
+		{
+			name:     "escaped keys",
+			from:     `{"a/b": 1, "c~d": 2}`,
+			to:       `{"a/b": 3}`,
+			expected: `[{"op": "remove", "path": "/c~0d"}, {"op": "replace", "path": "/a~1b", "value": 3}]`,
+		},
+		{
+			name:     "whole document",
+			from:     `{"a": 1}`,
+			to:       `"a"`,
+			expected: `[{"op": "replace", "path": "", "value": "a"}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var from, to interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.from), &from))
+			require.NoError(t, json.Unmarshal([]byte(test.to), &to))
+
+			patch := Diff(from, to)
+
+			actual, err := json.Marshal(patch)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(actual))
+
+			patched, err := patch.Apply(from)
+			require.NoError(t, err)
+			assert.True(t, Equal(to, patched), "applying the diff should produce the target document")
+		})
+	}
+}
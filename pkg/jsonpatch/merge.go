@@ -0,0 +1,28 @@
+package jsonpatch
+
+// MergePatch applies an RFC 7396 JSON Merge Patch to a copy of the target:
+// members of patch objects are merged recursively, null members are removed and any other value replaces the target.
+func MergePatch(target, patch interface{}) interface{} {
+	return mergePatch(deepCopy(target), deepCopy(patch))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
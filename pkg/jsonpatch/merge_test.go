@@ -0,0 +1,54 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Examples from RFC 7396, Appendix A
+func TestMergePatchRFC7396Examples(t *testing.T) {
+	var tests = []struct {
+		target   string
+		patch    string
+		expected string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.target+" + "+test.patch, func(t *testing.T) {
+			var target, patch interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.target), &target))
+			require.NoError(t, json.Unmarshal([]byte(test.patch), &patch))
+
+			actual, err := json.Marshal(MergePatch(target, patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(actual))
+		})
+	}
+}
+
+func TestMergePatchDoesNotModifyTarget(t *testing.T) {
+	target := map[string]interface{}{"a": map[string]interface{}{"b": "c"}}
+
+	MergePatch(target, map[string]interface{}{"a": map[string]interface{}{"b": nil}})
+
+	assert.Equal(t, map[string]interface{}{"a": map[string]interface{}{"b": "c"}}, target)
+}
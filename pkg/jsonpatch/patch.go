@@ -0,0 +1,333 @@
+// Package jsonpatch applies RFC 6902 JSON Patch and RFC 7396 JSON Merge Patch documents to decoded JSON content.
+package jsonpatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+)
+
+var (
+	ErrInvalidOperation = errors.New("invalid patch operation")
+	ErrInvalidPath      = errors.New("invalid JSON pointer")
+	ErrPathNotFound     = errors.New("path not found")
+	ErrTestFailed       = errors.New("test failed")
+)
+
+// Operation is a single JSON Patch operation
+type Operation struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// MarshalJSON only includes the members the operation defines
+func (o Operation) MarshalJSON() ([]byte, error) {
+	op := map[string]interface{}{"op": o.Op, "path": o.Path}
+	switch o.Op {
+	case "add", "replace", "test":
+		op["value"] = o.Value
+	case "move", "copy":
+		op["from"] = o.From
+	}
+	return json.Marshal(op)
+}
+
+// Patch is an ordered list of operations, applied atomically
+type Patch []Operation
+
+// OperationError reports the operation of a patch which could not be applied
+type OperationError struct {
+	Index int
+	Op    string
+	Path  string
+	Err   error
+}
+
+func (e *OperationError) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// Decode reads a JSON Patch document, checking every operation has the members its type requires
+func Decode(r io.Reader) (Patch, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	patch := make(Patch, len(raw))
+	for i, members := range raw {
+		op := &patch[i]
+		if err := decodeMember(members, "op", &op.Op); err != nil {
+			return nil, &OperationError{Index: i, Err: err}
+		}
+		if err := decodeMember(members, "path", &op.Path); err != nil {
+			return nil, &OperationError{Index: i, Op: op.Op, Err: err}
+		}
+
+		var err error
+		switch op.Op {
+		case "add", "replace", "test":
+			err = decodeMember(members, "value", &op.Value)
+		case "move", "copy":
+			err = decodeMember(members, "from", &op.From)
+		case "remove":
+		default:
+			err = ErrInvalidOperation
+		}
+		if err != nil {
+			return nil, &OperationError{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+	}
+	return patch, nil
+}
+
+func decodeMember(members map[string]json.RawMessage, name string, v interface{}) error {
+	raw, found := members[name]
+	if !found {
+		return fmt.Errorf("%w: missing %q", ErrInvalidOperation, name)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %q: %v", ErrInvalidOperation, name, err)
+	}
+	return nil
+}
+
+// Apply applies the patch to a copy of the document, so the document is left untouched if any operation fails
+func (p Patch) Apply(doc interface{}) (interface{}, error) {
+	doc = deepCopy(doc)
+	for i, op := range p {
+		var err error
+		doc, err = op.apply(doc)
+		if err != nil {
+			return nil, &OperationError{Index: i, Op: op.Op, Path: op.Path, Err: err}
+		}
+	}
+	return doc, nil
+}
+
+func (o Operation) apply(doc interface{}) (interface{}, error) {
+	path, err := ParsePointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.Op {
+	case "add":
+		return add(doc, path, deepCopy(o.Value))
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		return replace(doc, path, deepCopy(o.Value))
+	case "move":
+		from, err := ParsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		if from.isPrefixOf(path) && len(from) != len(path) {
+			return nil, fmt.Errorf("%w: cannot move a location into one of its children", ErrInvalidOperation)
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		from, err := ParsePointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+	case "test":
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !Equal(value, o.Value) {
+			return nil, ErrTestFailed
+		}
+		return doc, nil
+	}
+	return nil, ErrInvalidOperation
+}
+
+func add(doc interface{}, path Pointer, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return mutate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			if token == "-" {
+				return append(node, value), nil
+			}
+			i, err := arrayIndex(token, len(node))
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		}
+		return nil, ErrPathNotFound
+	})
+}
+
+func remove(doc interface{}, path Pointer) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: cannot remove the whole document", ErrInvalidOperation)
+	}
+	return mutate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, found := node[token]; !found {
+				return nil, ErrPathNotFound
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(node[:i], node[i+1:]...), nil
+		}
+		return nil, ErrPathNotFound
+	})
+}
+
+func replace(doc interface{}, path Pointer, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return mutate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, found := node[token]; !found {
+				return nil, ErrPathNotFound
+			}
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = value
+			return node, nil
+		}
+		return nil, ErrPathNotFound
+	})
+}
+
+// deepCopy copies objects and arrays, also converting named map and slice types (e.g. as decoded from BSON) to their JSON equivalents
+func deepCopy(v interface{}) interface{} {
+	switch node := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(node))
+		for k, child := range node {
+			c[k] = deepCopy(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(node))
+		for i, child := range node {
+			c[i] = deepCopy(child)
+		}
+		return c
+	}
+
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().ConvertibleTo(reflect.TypeOf(map[string]interface{}{})):
+		return deepCopy(rv.Convert(reflect.TypeOf(map[string]interface{}{})).Interface())
+	case rv.Kind() == reflect.Slice && rv.Type().ConvertibleTo(reflect.TypeOf([]interface{}{})):
+		return deepCopy(rv.Convert(reflect.TypeOf([]interface{}{})).Interface())
+	}
+	return v
+}
+
+// Equal compares two JSON values, treating numbers as equal when they have the same value regardless of their Go type
+func Equal(a, b interface{}) bool {
+	return equal(deepCopy(a), deepCopy(b))
+}
+
+func equal(a, b interface{}) bool {
+	if x, ok := toRat(a); ok {
+		y, ok := toRat(b)
+		return ok && x.Cmp(y) == 0
+	}
+
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, found := y[k]
+			if !found || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		return new(big.Rat).SetString(n.String())
+	case float64:
+		r := new(big.Rat)
+		if r.SetFloat64(n) == nil {
+			return nil, false
+		}
+		return r, true
+	case float32:
+		return toRat(float64(n))
+	case int:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int32:
+		return new(big.Rat).SetInt64(int64(n)), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	}
+	return nil, false
+}
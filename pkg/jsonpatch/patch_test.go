@@ -0,0 +1,236 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Examples from RFC 6902, Appendix A
+func TestApplyRFC6902Examples(t *testing.T) {
+	var tests = []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+		err      error
+	}{
+		{
+			name:     "A.1 adding an object member",
+			doc:      `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux"}]`,
+			expected: `{"baz": "qux", "foo": "bar"}`,
+		},
+		{
+			name:     "A.2 adding an array element",
+			doc:      `{"foo": ["bar", "baz"]}`,
+			patch:    `[{"op": "add", "path": "/foo/1", "value": "qux"}]`,
+			expected: `{"foo": ["bar", "qux", "baz"]}`,
+		},
+		{
+			name:     "A.3 removing an object member",
+			doc:      `{"baz": "qux", "foo": "bar"}`,
+			patch:    `[{"op": "remove", "path": "/baz"}]`,
+			expected: `{"foo": "bar"}`,
+		},
+		{
+			name:     "A.4 removing an array element",
+			doc:      `{"foo": ["bar", "qux", "baz"]}`,
+			patch:    `[{"op": "remove", "path": "/foo/1"}]`,
+			expected: `{"foo": ["bar", "baz"]}`,
+		},
+		{
+			name:     "A.5 replacing a value",
+			doc:      `{"baz": "qux", "foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "/baz", "value": "boo"}]`,
+			expected: `{"baz": "boo", "foo": "bar"}`,
+		},
+		{
+			name:     "A.6 moving a value",
+			doc:      `{"foo": {"bar": "baz", "waldo": "fred"}, "qux": {"corge": "grault"}}`,
+			patch:    `[{"op": "move", "from": "/foo/waldo", "path": "/qux/thud"}]`,
+			expected: `{"foo": {"bar": "baz"}, "qux": {"corge": "grault", "thud": "fred"}}`,
+		},
+		{
+			name:     "A.7 moving an array element",
+			doc:      `{"foo": ["all", "grass", "cows", "eat"]}`,
+			patch:    `[{"op": "move", "from": "/foo/1", "path": "/foo/3"}]`,
+			expected: `{"foo": ["all", "cows", "eat", "grass"]}`,
+		},
+		{
+			name:     "A.8 testing a value: success",
+			doc:      `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+			patch:    `[{"op": "test", "path": "/baz", "value": "qux"}, {"op": "test", "path": "/foo/1", "value": 2}]`,
+			expected: `{"baz": "qux", "foo": ["a", 2, "c"]}`,
+		},
+		{
+			name:  "A.9 testing a value: error",
+			doc:   `{"baz": "qux"}`,
+			patch: `[{"op": "test", "path": "/baz", "value": "bar"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:     "A.10 adding a nested member object",
+			doc:      `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/child", "value": {"grandchild": {}}}]`,
+			expected: `{"foo": "bar", "child": {"grandchild": {}}}`,
+		},
+		{
+			name:     "A.11 ignoring unrecognized elements",
+			doc:      `{"foo": "bar"}`,
+			patch:    `[{"op": "add", "path": "/baz", "value": "qux", "xyz": 123}]`,
+			expected: `{"foo": "bar", "baz": "qux"}`,
+		},
+		{
+			name:  "A.12 adding to a nonexistent target",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "add", "path": "/baz/bat", "value": "qux"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:     "A.14 ~ escape ordering",
+			doc:      `{"/": 9, "~1": 10}`,
+			patch:    `[{"op": "test", "path": "/~01", "value": 10}]`,
+			expected: `{"/": 9, "~1": 10}`,
+		},
+		{
+			name:  "A.15 comparing strings and numbers",
+			doc:   `{"/": 9, "~1": 10}`,
+			patch: `[{"op": "test", "path": "/~01", "value": "10"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:     "A.16 adding an array value",
+			doc:      `{"foo": ["bar"]}`,
+			patch:    `[{"op": "add", "path": "/foo/-", "value": ["abc", "def"]}]`,
+			expected: `{"foo": ["bar", ["abc", "def"]]}`,
+		},
+		{
+			name:     "copying a value",
+			doc:      `{"foo": {"bar": [1]}}`,
+			patch:    `[{"op": "copy", "from": "/foo/bar", "path": "/baz"}, {"op": "add", "path": "/baz/-", "value": 2}]`,
+			expected: `{"foo": {"bar": [1]}, "baz": [1, 2]}`,
+		},
+		{
+			name:     "changing the type of a value",
+			doc:      `{"foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "/foo", "value": {"bar": true}}]`,
+			expected: `{"foo": {"bar": true}}`,
+		},
+		{
+			name:     "replacing the whole document",
+			doc:      `{"foo": "bar"}`,
+			patch:    `[{"op": "replace", "path": "", "value": {"baz": 1}}]`,
+			expected: `{"baz": 1}`,
+		},
+		{
+			name:  "removing a missing member",
+			doc:   `{"foo": "bar"}`,
+			patch: `[{"op": "remove", "path": "/baz"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "replacing an index out of bounds",
+			doc:   `{"foo": ["bar"]}`,
+			patch: `[{"op": "replace", "path": "/foo/1", "value": "baz"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "array index with leading zeros",
+			doc:   `{"foo": ["bar", "baz"]}`,
+			patch: `[{"op": "remove", "path": "/foo/01"}]`,
+			err:   ErrInvalidPath,
+		},
+		{
+			name:  "moving a location into its child",
+			doc:   `{"foo": {"bar": 1}}`,
+			patch: `[{"op": "move", "from": "/foo", "path": "/foo/bar/baz"}]`,
+			err:   ErrInvalidOperation,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var doc interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.doc), &doc))
+
+			patch, err := Decode(strings.NewReader(test.patch))
+			require.NoError(t, err)
+
+			actual, err := patch.Apply(doc)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			data, err := json.Marshal(actual)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(data))
+		})
+	}
+}
+
+func TestApplyIsAtomic(t *testing.T) {
+	doc := map[string]interface{}{"foo": "bar"}
+	patch, err := Decode(strings.NewReader(`[{"op": "add", "path": "/baz", "value": 1}, {"op": "test", "path": "/foo", "value": "qux"}]`))
+	require.NoError(t, err)
+
+	_, err = patch.Apply(doc)
+
+	var opErr *OperationError
+	require.True(t, errors.As(err, &opErr))
+	assert.Equal(t, 1, opErr.Index)
+	assert.Equal(t, "test", opErr.Op)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, doc)
+}
+
+func TestDecodeInvalidPatch(t *testing.T) {
+	var tests = []struct {
+		name  string
+		patch string
+		index int
+	}{
+		{"unknown operation", `[{"op": "add", "path": "/a", "value": 1}, {"op": "merge", "path": "/a"}]`, 1},
+		{"missing value", `[{"op": "replace", "path": "/a"}]`, 0},
+		{"missing from", `[{"op": "move", "path": "/a"}]`, 0},
+		{"missing path", `[{"op": "remove"}]`, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Decode(strings.NewReader(test.patch))
+
+			var opErr *OperationError
+			require.True(t, errors.As(err, &opErr))
+			assert.ErrorIs(t, err, ErrInvalidOperation)
+			assert.Equal(t, test.index, opErr.Index)
+		})
+	}
+
+	_, err := Decode(strings.NewReader(`{"op": "add"}`))
+	assert.Error(t, err)
+}
+
+func TestParsePointer(t *testing.T) {
+	pointer, err := ParsePointer("/a~1b/m~0n/0")
+	require.NoError(t, err)
+	assert.Equal(t, Pointer{"a/b", "m~n", "0"}, pointer)
+	assert.Equal(t, "/a~1b/m~0n/0", pointer.String())
+
+	_, err = ParsePointer("a/b")
+	assert.ErrorIs(t, err, ErrInvalidPath)
+}
+
+func TestEqual(t *testing.T) {
+	assert.True(t, Equal(float64(2), int64(2)))
+	assert.True(t, Equal(json.Number("2.0"), float64(2)))
+	assert.True(t, Equal(map[string]interface{}{"a": []interface{}{1, "b"}}, map[string]interface{}{"a": []interface{}{float64(1), "b"}}))
+	assert.False(t, Equal("2", float64(2)))
+	assert.False(t, Equal([]interface{}{1, 2}, []interface{}{2, 1}))
+	assert.False(t, Equal(map[string]interface{}{"a": nil}, map[string]interface{}{}))
+}
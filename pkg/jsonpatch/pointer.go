@@ -0,0 +1,118 @@
+package jsonpatch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Pointer is a parsed RFC 6901 JSON pointer
+type Pointer []string
+
+// ParsePointer splits a JSON pointer into its unescaped reference tokens
+func ParsePointer(path string) (Pointer, error) {
+	if path == "" {
+		return Pointer{}, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, ErrInvalidPath
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// String escapes the reference tokens back into a JSON pointer
+func (p Pointer) String() string {
+	var sb strings.Builder
+	for _, token := range p {
+		sb.WriteString("/")
+		sb.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return sb.String()
+}
+
+func (p Pointer) isPrefixOf(other Pointer) bool {
+	if len(p) > len(other) {
+		return false
+	}
+	for i := range p {
+		if p[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses an array index token, which must not have leading zeros, and checks it is in [0, limit]
+func arrayIndex(token string, limit int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, ErrInvalidPath
+	}
+	for _, c := range token {
+		if c < '0' || c > '9' {
+			return 0, ErrInvalidPath
+		}
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, ErrInvalidPath
+	}
+	if i > limit {
+		return 0, ErrPathNotFound
+	}
+	return i, nil
+}
+
+// get returns the value the pointer references in the document
+func get(doc interface{}, pointer Pointer) (interface{}, error) {
+	for _, token := range pointer {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, found := node[token]
+			if !found {
+				return nil, ErrPathNotFound
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return doc, nil
+}
+
+// mutate applies f to the parent of the location the pointer references, replacing the parent with the value f returns
+func mutate(doc interface{}, pointer Pointer, f func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(pointer) == 1 {
+		return f(doc, pointer[0])
+	}
+
+	token := pointer[0]
+	child, err := get(doc, Pointer{token})
+	if err != nil {
+		return nil, err
+	}
+
+	child, err = mutate(child, pointer[1:], f)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		node[token] = child
+	case []interface{}:
+		i, _ := arrayIndex(token, len(node)-1)
+		node[i] = child
+	}
+	return doc, nil
+}
@@ -0,0 +1,91 @@
+package maintenance
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+const compactionProgressInterval = 500
+
+// CompactionResults counts what a compaction removed from a collection. Duplicates are only removed outside of dry runs.
+type CompactionResults struct {
+	Documents  int64 `json:"documents"`
+	Duplicates int64 `json:"duplicateRevisions"`
+	Removed    int64 `json:"removedRevisions"`
+	Reclaimed  int64 `json:"reclaimedBytes"`
+}
+
+// Compact removes from every document of a collection the revisions which are identical to the revision before them,
+// as written by republishing unchanged content. The latest revision of a document is always kept, so that its ETag stays valid.
+// progress is called every few hundred documents with the results so far.
+func Compact(ctx context.Context, connection db.Connection, collection string, dryRun bool, progress func(CompactionResults)) (CompactionResults, error) {
+	results := CompactionResults{}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ids, err := connection.ReadIDs(ctx, collection, true)
+	if err != nil {
+		return results, fmt.Errorf("reading the documents of %v: %w", collection, err)
+	}
+	// the documents left unread when stopping early must be drained for ReadIDs to finish
+	defer func() {
+		cancel()
+		for range ids {
+		}
+	}()
+
+	for id := range ids {
+		revisions, err := connection.ReadStoredRevisions(ctx, collection, id)
+		if err != nil {
+			return results, fmt.Errorf("reading the revisions of %v in %v: %w", id, collection, err)
+		}
+
+		duplicates, size := duplicateRevisions(revisions)
+		results.Documents++
+		results.Duplicates += int64(len(duplicates))
+
+		if len(duplicates) > 0 && !dryRun {
+			removed, err := connection.DeleteRevisions(ctx, collection, id, duplicates)
+			if err != nil {
+				return results, fmt.Errorf("removing the duplicate revisions of %v in %v: %w", id, collection, err)
+			}
+			results.Removed += removed
+			results.Reclaimed += size
+			logger.WithField("uuid", id).WithField("collection", collection).WithField("revisions", duplicates).Info("Removed duplicate revisions")
+		}
+
+		if progress != nil && results.Documents%compactionProgressInterval == 0 {
+			progress(results)
+		}
+	}
+
+	return results, ctx.Err()
+}
+
+// duplicateRevisions returns the revisions identical to the revision before them, except the latest one, and their total stored size.
+// The revisions must be in ascending order.
+func duplicateRevisions(revisions []db.StoredRevision) ([]int64, int64) {
+	var duplicates []int64
+	var size int64
+	for i := 1; i < len(revisions)-1; i++ {
+		if identicalRevisions(revisions[i-1].Resource, revisions[i].Resource) {
+			duplicates = append(duplicates, revisions[i].ContentRevision)
+			size += revisions[i].Size
+		}
+	}
+	return duplicates, size
+}
+
+func identicalRevisions(a, b *mapper.Resource) bool {
+	return a.ContentType == b.ContentType &&
+		a.OriginSystemID == b.OriginSystemID &&
+		a.SchemaVersion == b.SchemaVersion &&
+		a.Deleted == b.Deleted &&
+		reflect.DeepEqual(a.Content, b.Content)
+}
@@ -0,0 +1,99 @@
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func storedRevision(revision int64, content interface{}, schemaVersion string) db.StoredRevision {
+	return db.StoredRevision{
+		Resource: &mapper.Resource{
+			UUID:            "a",
+			Content:         content,
+			ContentType:     "application/json",
+			SchemaVersion:   schemaVersion,
+			ContentRevision: revision,
+		},
+		Size: 100,
+	}
+}
+
+func TestDuplicateRevisions(t *testing.T) {
+	revisions := []db.StoredRevision{
+		storedRevision(1, map[string]interface{}{"title": "a"}, "1"),
+		storedRevision(2, map[string]interface{}{"title": "a"}, "1"),
+		storedRevision(3, map[string]interface{}{"title": "a"}, "1"),
+		storedRevision(4, map[string]interface{}{"title": "a"}, "2"),
+		storedRevision(5, map[string]interface{}{"title": "b"}, "2"),
+		storedRevision(6, map[string]interface{}{"title": "b"}, "2"),
+	}
+
+	duplicates, size := duplicateRevisions(revisions)
+	assert.Equal(t, []int64{2, 3}, duplicates)
+	assert.Equal(t, int64(200), size)
+}
+
+func TestDuplicateRevisionsKeepsDeletions(t *testing.T) {
+	tombstone := db.StoredRevision{Resource: mapper.Tombstone("a", "application/json", "", "1", 2)}
+	revisions := []db.StoredRevision{
+		storedRevision(1, nil, "1"),
+		tombstone,
+		storedRevision(3, nil, "1"),
+	}
+
+	duplicates, _ := duplicateRevisions(revisions)
+	assert.Empty(t, duplicates)
+}
+
+func TestCompact(t *testing.T) {
+	revisions := []db.StoredRevision{
+		storedRevision(1, map[string]interface{}{"title": "a"}, "1"),
+		storedRevision(2, map[string]interface{}{"title": "a"}, "1"),
+		storedRevision(3, map[string]interface{}{"title": "a"}, "1"),
+	}
+
+	connection := new(mockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a", "b"), nil)
+	connection.On("ReadStoredRevisions", mock.Anything, "universal-content", "a").Return(revisions, nil)
+	connection.On("ReadStoredRevisions", mock.Anything, "universal-content", "b").Return(revisions[:1], nil)
+	connection.On("DeleteRevisions", mock.Anything, "universal-content", "a", []int64{2}).Return(int64(1), nil)
+
+	results, err := Compact(context.Background(), connection, "universal-content", false, nil)
+	assert.NoError(t, err)
+	connection.AssertExpectations(t)
+	assert.Equal(t, CompactionResults{Documents: 2, Duplicates: 1, Removed: 1, Reclaimed: 100}, results)
+}
+
+func TestCompactDryRun(t *testing.T) {
+	revisions := []db.StoredRevision{
+		storedRevision(1, map[string]interface{}{"title": "a"}, "1"),
+		storedRevision(2, map[string]interface{}{"title": "a"}, "1"),
+		storedRevision(3, map[string]interface{}{"title": "a"}, "1"),
+	}
+
+	connection := new(mockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a"), nil)
+	connection.On("ReadStoredRevisions", mock.Anything, "universal-content", "a").Return(revisions, nil)
+
+	results, err := Compact(context.Background(), connection, "universal-content", true, nil)
+	assert.NoError(t, err)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "DeleteRevisions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, CompactionResults{Documents: 1, Duplicates: 1}, results)
+}
+
+func TestCompactFails(t *testing.T) {
+	connection := new(mockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a", "b"), nil)
+	connection.On("ReadStoredRevisions", mock.Anything, "universal-content", "a").Return([]db.StoredRevision(nil), errors.New("i failed"))
+
+	_, err := Compact(context.Background(), connection, "universal-content", false, nil)
+	assert.ErrorContains(t, err, "i failed")
+}
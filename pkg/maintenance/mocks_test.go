@@ -0,0 +1,47 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+// mockConnection only implements the methods used by the jobs, the others panic
+type mockConnection struct {
+	db.Connection
+	mock.Mock
+}
+
+func (m *mockConnection) ReadIDs(ctx context.Context, collection string, includeDeleted bool) (chan string, error) {
+	args := m.Called(ctx, collection, includeDeleted)
+	return args.Get(0).(chan string), args.Error(1)
+}
+
+func (m *mockConnection) ReadRevisionsMetadata(ctx context.Context, collection string, uuidString string) ([]*mapper.Resource, error) {
+	args := m.Called(ctx, collection, uuidString)
+	return args.Get(0).([]*mapper.Resource), args.Error(1)
+}
+
+func (m *mockConnection) ReadStoredRevisions(ctx context.Context, collection string, uuidString string) ([]db.StoredRevision, error) {
+	args := m.Called(ctx, collection, uuidString)
+	return args.Get(0).([]db.StoredRevision), args.Error(1)
+}
+
+func (m *mockConnection) DeleteRevisions(ctx context.Context, collection string, uuidString string, revisions []int64) (int64, error) {
+	args := m.Called(ctx, collection, uuidString, revisions)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockConnection) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	args := m.Called(ctx, name, holder, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockConnection) ReleaseLease(ctx context.Context, name string, holder string, expires time.Time) error {
+	args := m.Called(ctx, name, holder, expires)
+	return args.Error(0)
+}
@@ -0,0 +1,318 @@
+// Package maintenance runs the background jobs tidying up the revisions in the native store.
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/config"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+// retentionMetrics are published on /debug/vars
+var retentionMetrics = expvar.NewMap("retention")
+
+const (
+	// retentionLease is the name of the lease an instance of the service takes to run the retention job
+	retentionLease = "retention"
+	// leaseReleaseTimeout bounds the release of the lease, which also happens when the run is cancelled
+	leaseReleaseTimeout = 5 * time.Second
+)
+
+// errLeaseLost stops a run when another instance took over the lease of the job
+var errLeaseLost = errors.New("the retention lease was taken over by another instance")
+
+// RetentionJob deletes the revisions which have expired under the retention rules of their collection.
+// Every instance of the service schedules it, and the instance holding the lease of the job runs it.
+type RetentionJob struct {
+	connection db.Connection
+	policies   map[string]config.Retention
+	interval   time.Duration
+	dryRun     bool
+	holder     string
+	now        func() time.Time
+
+	// leaseRenewed is when the running instance last renewed its lease, and is only used by the run
+	leaseRenewed time.Time
+
+	mu     sync.Mutex
+	status RetentionStatus
+}
+
+// RetentionStatus describes the current or last run of the retention job
+type RetentionStatus struct {
+	DryRun      bool                         `json:"dryRun"`
+	Running     bool                         `json:"running"`
+	Started     *time.Time                   `json:"started,omitempty"`
+	Finished    *time.Time                   `json:"finished,omitempty"`
+	Error       string                       `json:"error,omitempty"`
+	Collections map[string]*RetentionResults `json:"collections,omitempty"`
+}
+
+// RetentionResults counts what the retention job did in a collection. Expired revisions are only deleted outside of dry runs.
+type RetentionResults struct {
+	Documents int64 `json:"documents"`
+	Expired   int64 `json:"expiredRevisions"`
+	Deleted   int64 `json:"deletedRevisions"`
+}
+
+// NewRetentionJob creates a job applying the retention rules of each collection every interval
+func NewRetentionJob(connection db.Connection, policies map[string]config.Retention, interval time.Duration, dryRun bool) *RetentionJob {
+	return &RetentionJob{
+		connection: connection,
+		policies:   policies,
+		interval:   interval,
+		dryRun:     dryRun,
+		holder:     leaseHolder(),
+		now:        time.Now,
+		status:     RetentionStatus{DryRun: dryRun},
+	}
+}
+
+// Start runs the job straight away and then every interval, until the context is done
+func (j *RetentionJob) Start(ctx context.Context) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := j.runIfLeased(ctx); err != nil {
+			logger.WithError(err).Error("Retention job failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runIfLeased runs the job if this instance gets the lease of the job, so that the instances of the service do not all run it.
+// The lease lasts twice the interval, and is renewed every half interval during the run, so that it cannot expire while the job runs.
+// When the run ends, the lease is released for the next run, which is due an interval after this one started.
+func (j *RetentionJob) runIfLeased(ctx context.Context) error {
+	started := j.now()
+	leased, err := j.connection.AcquireLease(ctx, retentionLease, j.holder, j.leaseTTL())
+	if err != nil {
+		return fmt.Errorf("acquiring the retention lease: %w", err)
+	}
+	if !leased {
+		retentionMetrics.Add("skippedRuns", 1)
+		logger.WithField("holder", j.holder).Info("Retention job skipped, another instance holds the lease")
+		return nil
+	}
+	j.leaseRenewed = started
+
+	err = j.run(ctx, j.renewLease)
+
+	releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), leaseReleaseTimeout)
+	defer cancel()
+	if releaseErr := j.connection.ReleaseLease(releaseCtx, retentionLease, j.holder, started.Add(j.interval)); releaseErr != nil {
+		logger.WithError(releaseErr).WithField("holder", j.holder).Warn("Releasing the retention lease failed, it expires on its own")
+	}
+	return err
+}
+
+// leaseTTL is how long the lease of the job lasts without being renewed
+func (j *RetentionJob) leaseTTL() time.Duration {
+	return 2 * j.interval
+}
+
+// renewLease renews the lease of the job once half an interval has passed since it was last renewed, and fails if another instance took it over
+func (j *RetentionJob) renewLease(ctx context.Context) error {
+	now := j.now()
+	if now.Sub(j.leaseRenewed) < j.interval/2 {
+		return nil
+	}
+
+	leased, err := j.connection.AcquireLease(ctx, retentionLease, j.holder, j.leaseTTL())
+	if err != nil {
+		return fmt.Errorf("renewing the retention lease: %w", err)
+	}
+	if !leased {
+		return errLeaseLost
+	}
+	j.leaseRenewed = now
+	return nil
+}
+
+// leaseHolder identifies this instance of the service as the holder of a lease
+func leaseHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "nativerw"
+	}
+	return hostname + "-" + uuid.New()
+}
+
+// Run applies the retention rules once to every document of the configured collections
+func (j *RetentionJob) Run(ctx context.Context) error {
+	return j.run(ctx, nil)
+}
+
+// run applies the retention rules, calling renew, if given, before each document to keep the lease of the job
+func (j *RetentionJob) run(ctx context.Context, renew func(ctx context.Context) error) error {
+	started := j.now()
+	j.mu.Lock()
+	if j.status.Running {
+		j.mu.Unlock()
+		return fmt.Errorf("retention job already running since %v", j.status.Started)
+	}
+	j.status = RetentionStatus{DryRun: j.dryRun, Running: true, Started: &started, Collections: map[string]*RetentionResults{}}
+	j.mu.Unlock()
+
+	retentionMetrics.Add("runs", 1)
+	logger.WithField("dryRun", j.dryRun).Info("Retention job started")
+
+	collections := make([]string, 0, len(j.policies))
+	for collection := range j.policies {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	var err error
+	for _, collection := range collections {
+		if err = j.applyRetention(ctx, collection, j.policies[collection], renew); err != nil {
+			break
+		}
+	}
+
+	finished := j.now()
+	j.mu.Lock()
+	j.status.Running = false
+	j.status.Finished = &finished
+	if err != nil {
+		j.status.Error = err.Error()
+		retentionMetrics.Add("errors", 1)
+	}
+	j.mu.Unlock()
+
+	logger.WithField("dryRun", j.dryRun).WithField("duration", finished.Sub(started).String()).Info("Retention job finished")
+	return err
+}
+
+func (j *RetentionJob) applyRetention(ctx context.Context, collection string, policy config.Retention, renew func(ctx context.Context) error) error {
+	results := &RetentionResults{}
+	j.mu.Lock()
+	j.status.Collections[collection] = results
+	j.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ids, err := j.connection.ReadIDs(ctx, collection, true)
+	if err != nil {
+		return fmt.Errorf("reading the documents of %v: %w", collection, err)
+	}
+	// the documents left unread when stopping early must be drained for ReadIDs to finish
+	defer func() {
+		cancel()
+		for range ids {
+		}
+	}()
+
+	for id := range ids {
+		if renew != nil {
+			if err := renew(ctx); err != nil {
+				return err
+			}
+		}
+
+		revisions, err := j.connection.ReadRevisionsMetadata(ctx, collection, id)
+		if err != nil {
+			return fmt.Errorf("reading the revisions of %v in %v: %w", id, collection, err)
+		}
+
+		expired := expiredRevisions(revisions, policy, j.now())
+
+		var deleted int64
+		if len(expired) > 0 && !j.dryRun {
+			deleted, err = j.connection.DeleteRevisions(ctx, collection, id, expired)
+			if err != nil {
+				return fmt.Errorf("deleting the expired revisions of %v in %v: %w", id, collection, err)
+			}
+			logger.WithField("uuid", id).WithField("collection", collection).WithField("revisions", expired).Info("Deleted expired revisions")
+		}
+
+		j.mu.Lock()
+		results.Documents++
+		results.Expired += int64(len(expired))
+		results.Deleted += deleted
+		j.mu.Unlock()
+
+		retentionMetrics.Add("expiredRevisions", int64(len(expired)))
+		retentionMetrics.Add("deletedRevisions", deleted)
+	}
+
+	return ctx.Err()
+}
+
+// expiredRevisions returns the revisions which no retention rule keeps. The revisions must be in ascending order.
+// Deletions are always kept, together with the newest revision before each of them, which is the one an undelete restores.
+func expiredRevisions(revisions []*mapper.Resource, policy config.Retention, now time.Time) []int64 {
+	if policy.KeepLast <= 0 && policy.KeepNewerThan.Duration <= 0 {
+		return nil
+	}
+
+	keepFrom := len(revisions) - 1
+	if policy.KeepLast > 1 {
+		keepFrom = len(revisions) - policy.KeepLast
+	}
+	var newerThan int64
+	if policy.KeepNewerThan.Duration > 0 {
+		newerThan = now.Add(-policy.KeepNewerThan.Duration).UnixNano()
+	}
+
+	var expired []int64
+	for i, revision := range revisions {
+		switch {
+		case i >= keepFrom:
+		case revision.Deleted:
+		case i+1 < len(revisions) && revisions[i+1].Deleted:
+		case newerThan > 0 && revision.ContentRevision > newerThan:
+		default:
+			expired = append(expired, revision.ContentRevision)
+		}
+	}
+	return expired
+}
+
+// Status returns a snapshot of the current or last run
+func (j *RetentionJob) Status() RetentionStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := j.status
+	status.Collections = make(map[string]*RetentionResults, len(j.status.Collections))
+	for collection, results := range j.status.Collections {
+		r := *results
+		status.Collections[collection] = &r
+	}
+	return status
+}
+
+// StatusHandler serves the status of the retention job
+func (j *RetentionJob) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(j.Status())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		logger.WithError(err).Error("could not write the retention status")
+	}
+}
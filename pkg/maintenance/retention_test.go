@@ -0,0 +1,231 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/config"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func init() {
+	logger.InitLogger("nativerw", "error")
+}
+
+var now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func revisionsAt(deleted map[int]bool, ages ...time.Duration) []*mapper.Resource {
+	revisions := []*mapper.Resource{}
+	for i, age := range ages {
+		revisions = append(revisions, &mapper.Resource{ContentRevision: now.Add(-age).UnixNano(), Deleted: deleted[i]})
+	}
+	return revisions
+}
+
+func revisionNumbers(revisions []*mapper.Resource, indexes ...int) []int64 {
+	var numbers []int64
+	for _, i := range indexes {
+		numbers = append(numbers, revisions[i].ContentRevision)
+	}
+	return numbers
+}
+
+func ids(values ...string) chan string {
+	ch := make(chan string, len(values))
+	for _, value := range values {
+		ch <- value
+	}
+	close(ch)
+	return ch
+}
+
+func TestExpiredRevisions(t *testing.T) {
+	day := 24 * time.Hour
+	revisions := revisionsAt(map[int]bool{1: true}, 50*day, 40*day, 30*day, 20*day, 10*day, day)
+
+	tests := []struct {
+		name     string
+		policy   config.Retention
+		expected []int64
+	}{
+		{
+			name:     "no rules keeps everything",
+			policy:   config.Retention{},
+			expected: nil,
+		},
+		{
+			name:     "keep last",
+			policy:   config.Retention{KeepLast: 3},
+			expected: revisionNumbers(revisions, 2),
+		},
+		{
+			name:     "keep newer than",
+			policy:   config.Retention{KeepNewerThan: config.Duration{Duration: 15 * day}},
+			expected: revisionNumbers(revisions, 2, 3),
+		},
+		{
+			name:     "a revision is kept if any rule keeps it",
+			policy:   config.Retention{KeepLast: 2, KeepNewerThan: config.Duration{Duration: 25 * day}},
+			expected: revisionNumbers(revisions, 2),
+		},
+		{
+			name:     "the latest revision is always kept",
+			policy:   config.Retention{KeepNewerThan: config.Duration{Duration: time.Hour}},
+			expected: revisionNumbers(revisions, 2, 3, 4),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, expiredRevisions(revisions, test.policy, now))
+		})
+	}
+}
+
+func TestExpiredRevisionsKeepRevisionsBeforeDeletions(t *testing.T) {
+	day := 24 * time.Hour
+	revisions := revisionsAt(map[int]bool{2: true, 3: true, 5: true}, 50*day, 40*day, 30*day, 20*day, 10*day, day)
+
+	expired := expiredRevisions(revisions, config.Retention{KeepLast: 1}, now)
+	assert.Equal(t, revisionNumbers(revisions, 0), expired, "the revisions restored by an undelete are kept")
+}
+
+func TestRetentionJobRun(t *testing.T) {
+	revisions := revisionsAt(nil, 3*time.Hour, 2*time.Hour, time.Hour)
+
+	connection := new(mockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a", "b"), nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a").Return(revisions, nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "b").Return(revisions[2:], nil)
+	connection.On("DeleteRevisions", mock.Anything, "universal-content", "a", revisionNumbers(revisions, 0, 1)).Return(int64(2), nil)
+
+	job := NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 1}}, time.Hour, false)
+	job.now = func() time.Time { return now }
+
+	err := job.Run(context.Background())
+	assert.NoError(t, err)
+	connection.AssertExpectations(t)
+
+	status := job.Status()
+	assert.False(t, status.Running)
+	assert.Empty(t, status.Error)
+	assert.Equal(t, &RetentionResults{Documents: 2, Expired: 2, Deleted: 2}, status.Collections["universal-content"])
+}
+
+func TestRetentionJobDryRun(t *testing.T) {
+	revisions := revisionsAt(nil, 3*time.Hour, 2*time.Hour, time.Hour)
+
+	connection := new(mockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a"), nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a").Return(revisions, nil)
+
+	job := NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 2}}, time.Hour, true)
+	job.now = func() time.Time { return now }
+
+	err := job.Run(context.Background())
+	assert.NoError(t, err)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "DeleteRevisions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	status := job.Status()
+	assert.True(t, status.DryRun)
+	assert.Equal(t, &RetentionResults{Documents: 1, Expired: 1}, status.Collections["universal-content"])
+}
+
+func TestRetentionJobFailure(t *testing.T) {
+	connection := new(mockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a"), nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a").Return([]*mapper.Resource(nil), errors.New("i failed"))
+
+	job := NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 1}}, time.Hour, false)
+
+	err := job.Run(context.Background())
+	assert.ErrorContains(t, err, "i failed")
+
+	w := httptest.NewRecorder()
+	job.StatusHandler(w, httptest.NewRequest("GET", "/__retention", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var status RetentionStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.False(t, status.Running)
+	assert.NotNil(t, status.Finished)
+	assert.Contains(t, status.Error, "i failed")
+}
+
+func TestRetentionJobRunsWithLease(t *testing.T) {
+	connection := new(mockConnection)
+	connection.On("AcquireLease", mock.Anything, "retention", mock.AnythingOfType("string"), 2*time.Hour).Return(true, nil).Once()
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids(), nil)
+	connection.On("ReleaseLease", mock.Anything, "retention", mock.AnythingOfType("string"), now.Add(time.Hour)).Return(nil)
+
+	job := NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 1}}, time.Hour, false)
+	job.now = func() time.Time { return now }
+
+	err := job.runIfLeased(context.Background())
+	assert.NoError(t, err)
+	connection.AssertExpectations(t)
+	assert.NotNil(t, job.Status().Finished)
+}
+
+func TestRetentionJobRenewsLeaseDuringRun(t *testing.T) {
+	clock := now
+	connection := new(mockConnection)
+	connection.On("AcquireLease", mock.Anything, "retention", mock.AnythingOfType("string"), 2*time.Hour).Return(true, nil).Times(3)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a", "b", "c"), nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", mock.Anything).
+		Run(func(mock.Arguments) { clock = clock.Add(40 * time.Minute) }).
+		Return([]*mapper.Resource{}, nil)
+	connection.On("ReleaseLease", mock.Anything, "retention", mock.AnythingOfType("string"), now.Add(time.Hour)).Return(nil)
+
+	job := NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 1}}, time.Hour, false)
+	job.now = func() time.Time { return clock }
+
+	err := job.runIfLeased(context.Background())
+	assert.NoError(t, err)
+	connection.AssertExpectations(t)
+	assert.Equal(t, int64(3), job.Status().Collections["universal-content"].Documents)
+}
+
+func TestRetentionJobStopsWhenLeaseLost(t *testing.T) {
+	clock := now
+	connection := new(mockConnection)
+	connection.On("AcquireLease", mock.Anything, "retention", mock.AnythingOfType("string"), 2*time.Hour).Return(true, nil).Once()
+	connection.On("AcquireLease", mock.Anything, "retention", mock.AnythingOfType("string"), 2*time.Hour).Return(false, nil).Once()
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids("a", "b", "c"), nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a").
+		Run(func(mock.Arguments) { clock = clock.Add(40 * time.Minute) }).
+		Return([]*mapper.Resource{}, nil)
+	connection.On("ReleaseLease", mock.Anything, "retention", mock.AnythingOfType("string"), now.Add(time.Hour)).Return(nil)
+
+	job := NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 1}}, time.Hour, false)
+	job.now = func() time.Time { return clock }
+
+	err := job.runIfLeased(context.Background())
+	assert.ErrorIs(t, err, errLeaseLost)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "ReadRevisionsMetadata", mock.Anything, "universal-content", "b")
+}
+
+func TestRetentionJobSkippedWithoutLease(t *testing.T) {
+	connection := new(mockConnection)
+	connection.On("AcquireLease", mock.Anything, "retention", mock.AnythingOfType("string"), 2*time.Hour).Return(false, nil)
+
+	job := NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 1}}, time.Hour, false)
+
+	err := job.runIfLeased(context.Background())
+	assert.NoError(t, err)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "ReadIDs", mock.Anything, mock.Anything, mock.Anything)
+	assert.Nil(t, job.Status().Started)
+}
@@ -4,11 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
-	"strings"
 )
 
 var (
 	ErrUnsupportedContentType = errors.New("unsupported content-type, no mapping implementation")
+	ErrNotRawContent          = errors.New("content is not raw bytes")
 )
 
 // Resource is the representation of a native resource
@@ -19,6 +19,15 @@ type Resource struct {
 	OriginSystemID  string
 	SchemaVersion   string
 	ContentRevision int64
+	// Deleted marks a tombstone, the revision recording the deletion of the document
+	Deleted bool
+	// ContentHash and CanonicalHash are the hashes of the content expected in X-Native-Hash with the sha224 and sha256-jcs algorithms,
+	// computed when the revision is written. They are empty for tombstones, and for revisions written before hashes were stored which have not been backfilled.
+	// CanonicalHash is also empty for content with numbers RFC 8785 cannot represent, e.g. 1E400.
+	ContentHash   string
+	CanonicalHash string
+	// ContentSize is the size in bytes of the serialisation hashed by ContentHash, stored with it
+	ContentSize int64
 }
 
 // Wrap creates a new resource
@@ -33,15 +42,29 @@ func Wrap(content interface{}, resourceID, contentType, originSystemID, schemaVe
 	}
 }
 
+// Tombstone creates a new resource recording the deletion of a document
+func Tombstone(resourceID, contentType, originSystemID, schemaVersion string, contentRevision int64) *Resource {
+	return &Resource{
+		UUID:            resourceID,
+		ContentType:     contentType,
+		OriginSystemID:  originSystemID,
+		SchemaVersion:   schemaVersion,
+		ContentRevision: contentRevision,
+		Deleted:         true,
+	}
+}
+
 // OutMapper writes a resource in the required content format
 type OutMapper func(io.Writer, *Resource) error
 
+// OutMapperForContentType returns the OutMapper registered for the content type. By default, JSON variants are written from their decoded content,
+// and any other valid content type from the raw bytes received.
 func OutMapperForContentType(contentType string) (OutMapper, error) {
-	if isApplicationJSONVariantWithDirectives(contentType) {
-		return jsonVariantOutMapper, nil
+	m, err := lookup(contentType)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, ErrUnsupportedContentType
+	return m.out, nil
 }
 
 func jsonVariantOutMapper(w io.Writer, resource *Resource) error {
@@ -49,44 +72,45 @@ func jsonVariantOutMapper(w io.Writer, resource *Resource) error {
 	return encoder.Encode(resource.Content)
 }
 
+func rawOutMapper(w io.Writer, resource *Resource) error {
+	data, ok := resource.Content.([]byte)
+	if !ok {
+		return ErrNotRawContent
+	}
+	_, err := w.Write(data)
+	return err
+}
+
 // InMapper marshals the transport format into a resource
 type InMapper func(io.ReadCloser) (interface{}, error)
 
-// InMapperForContentType checks the content type if it's a json variant
-// and returns an InMapper.
+// InMapperForContentType returns the InMapper registered for the content type. By default, JSON variants are decoded,
+// and any other valid content type is kept as the raw bytes received.
 func InMapperForContentType(contentType string) (InMapper, error) {
-	if isApplicationJSONVariantWithDirectives(contentType) {
-		return jsonVariantInMapper, nil
+	m, err := lookup(contentType)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, ErrUnsupportedContentType
+	return m.in, nil
 }
 
+// jsonVariantInMapper decodes the numbers as json.Number, so that they are stored and served exactly as they were written
 func jsonVariantInMapper(r io.ReadCloser) (interface{}, error) {
 	var c map[string]interface{}
 	defer r.Close()
-	err := json.NewDecoder(r).Decode(&c)
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	err := decoder.Decode(&c)
 	return c, err
 }
 
-func isApplicationJSONVariantWithDirectives(contentType string) bool {
-	contentType = stripDirectives(contentType)
-
-	if contentType == "application/json" {
-		return true
-	}
-
-	if strings.HasPrefix(contentType, "application/") &&
-		strings.HasSuffix(contentType, "+json") {
-		return true
-	}
-
-	return false
+func rawInMapper(r io.ReadCloser) (interface{}, error) {
+	defer r.Close()
+	return io.ReadAll(r)
 }
 
-func stripDirectives(contentType string) string {
-	if strings.Contains(contentType, ";") {
-		contentType = strings.Split(contentType, ";")[0]
-	}
-	return contentType
+// IsJSON tells whether the content of a content type is decoded as JSON by its registered in mapper, rather than kept as raw bytes
+func IsJSON(contentType string) bool {
+	m, err := lookup(contentType)
+	return err == nil && m.json
 }
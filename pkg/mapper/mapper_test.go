@@ -2,6 +2,7 @@ package mapper
 
 import (
 	"bytes"
+	"encoding/json"
 	"reflect"
 	"strings"
 	"testing"
@@ -10,8 +11,7 @@ import (
 )
 
 const (
-	articleCt      = "application/vnd.ft-upp-article+json; version=1.0; charset=utf-8"
-	articlePlainCt = "application/json; version=1.0; charset=utf-8"
+	articleCt = "application/vnd.ft-upp-article+json; version=1.0; charset=utf-8"
 
 	textPlainCt = "text/plain; charset=iso-8859-1"
 )
@@ -99,9 +99,67 @@ func TestJsonMappers(t *testing.T) {
 	mockBody.AssertExpectations(t)
 }
 
-func TestApplicationJsonVariantEval(t *testing.T) {
-	assert.True(t, isApplicationJSONVariantWithDirectives(articleCt))
-	assert.True(t, isApplicationJSONVariantWithDirectives(articlePlainCt))
+func TestJsonMappersKeepNumbers(t *testing.T) {
+	payload := `{"id":9007199254740993,"price":3.141592653589793238462643383279,"ratio":1.50,"count":2}`
+	mockBody := &MockBody{Body: strings.NewReader(payload)}
+	mockBody.On("Close").Return(nil)
+	mockBody.On("Read").Return(nil)
+
+	inMapper, err := InMapperForContentType("application/json")
+	assert.NoError(t, err)
+
+	content, err := inMapper(mockBody)
+	assert.NoError(t, err)
+	assert.Equal(t, json.Number("9007199254740993"), content.(map[string]interface{})["id"])
+	assert.Equal(t, json.Number("3.141592653589793238462643383279"), content.(map[string]interface{})["price"])
+
+	var writer = bytes.NewBuffer([]byte{})
+	outMapper, _ := OutMapperForContentType("application/json")
+	err = outMapper(writer, &Resource{Content: content})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"count":2,"id":9007199254740993,"price":3.141592653589793238462643383279,"ratio":1.50}`, strings.TrimSpace(writer.String()))
+	mockBody.AssertExpectations(t)
+}
+
+func TestRawMappers(t *testing.T) {
+	data := []byte{0x00, 0x01, 0xfe, 0xff, '<', 'x', '/', '>'}
+	mockBody := &MockBody{Body: bytes.NewReader(data)}
+	mockBody.On("Close").Return(nil)
+	mockBody.On("Read").Return(nil)
+
+	inMapper, err := InMapperForContentType("application/octet-stream")
+	assert.NoError(t, err)
+
+	content, err := inMapper(mockBody)
+	assert.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	var writer = bytes.NewBuffer([]byte{})
+	outMapper, err := OutMapperForContentType("application/octet-stream")
+	assert.NoError(t, err)
+	err = outMapper(writer, &Resource{Content: content})
+
+	assert.NoError(t, err)
+	assert.Equal(t, data, writer.Bytes())
+	mockBody.AssertExpectations(t)
+}
+
+func TestRawOutMapperWithDecodedContent(t *testing.T) {
+	outMapper, err := OutMapperForContentType(textPlainCt)
+	assert.NoError(t, err)
+
+	err = outMapper(bytes.NewBuffer([]byte{}), &Resource{Content: map[string]interface{}{"title": "Title"}})
+	assert.ErrorIs(t, err, ErrNotRawContent)
+}
+
+func TestMappersForInvalidContentType(t *testing.T) {
+	_, err := InMapperForContentType("not a media type")
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+
+	_, err = OutMapperForContentType("")
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
 
-	assert.False(t, isApplicationJSONVariantWithDirectives(textPlainCt))
+	assert.True(t, IsJSON(articleCt))
+	assert.False(t, IsJSON(textPlainCt))
 }
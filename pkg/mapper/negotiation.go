@@ -0,0 +1,221 @@
+package mapper
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotAcceptable is returned when none of the media types accepted by the client can render the content
+var ErrNotAcceptable = errors.New("no acceptable content-type")
+
+// rendering is an encoding JSON content can be served in, whatever its stored content type.
+// The first media type is the one answered when the client accepts a wildcard.
+type rendering struct {
+	mediaTypes []string
+	mapper     OutMapper
+}
+
+const jsonMediaType = "application/json"
+
+var jsonRenderings = []rendering{
+	{mediaTypes: []string{jsonMediaType}, mapper: jsonVariantOutMapper},
+	{mediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"}, mapper: yamlOutMapper},
+	{mediaTypes: []string{"application/cbor"}, mapper: cborOutMapper},
+	{mediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}, mapper: msgpackOutMapper},
+}
+
+// acceptedRange is a media range of an Accept header, e.g. application/*, with its quality
+type acceptedRange struct {
+	mediaType string
+	quality   float64
+}
+
+// NegotiateOutMapper returns the OutMapper and the content type answering a request with the given Accept header,
+// for a content stored with the given content type. The stored content type is preferred, and JSON content can also be rendered as JSON, YAML, CBOR or MessagePack.
+// JSON variants accepted as application/json are served with their stored content type.
+func NegotiateOutMapper(contentType, accept string) (OutMapper, string, error) {
+	storedMapper, err := OutMapperForContentType(contentType)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.TrimSpace(accept) == "" {
+		return storedMapper, contentType, nil
+	}
+
+	storedType, _, _ := mime.ParseMediaType(contentType)
+	ranges, excluded := parseAccept(accept)
+	for _, accepted := range ranges {
+		if accepted.matches(storedType) && !excluded[storedType] {
+			return storedMapper, contentType, nil
+		}
+		if !IsJSON(contentType) {
+			continue
+		}
+
+		for _, r := range jsonRenderings {
+			for _, mediaType := range r.mediaTypes {
+				if !accepted.matches(mediaType) || excluded[mediaType] {
+					continue
+				}
+				// a JSON variant is JSON already, so it keeps its more specific type unless the client refused it
+				if mediaType == jsonMediaType && !excluded[storedType] {
+					return storedMapper, contentType, nil
+				}
+				return r.mapper, mediaType, nil
+			}
+		}
+	}
+
+	return nil, "", ErrNotAcceptable
+}
+
+// parseAccept returns the media ranges of an Accept header from the most to the least preferred,
+// and the media types explicitly refused with a zero quality. Invalid media ranges are ignored.
+func parseAccept(accept string) ([]acceptedRange, map[string]bool) {
+	var ranges []acceptedRange
+	excluded := map[string]bool{}
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := params["q"]; found {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality <= 0 {
+			excluded[mediaType] = true
+			continue
+		}
+		ranges = append(ranges, acceptedRange{mediaType: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+	return ranges, excluded
+}
+
+func (a acceptedRange) matches(mediaType string) bool {
+	if a.mediaType == "*/*" || a.mediaType == mediaType {
+		return true
+	}
+	if prefix, found := strings.CutSuffix(a.mediaType, "/*"); found {
+		return strings.HasPrefix(mediaType, prefix+"/")
+	}
+	return false
+}
+
+func yamlOutMapper(w io.Writer, resource *Resource) error {
+	node, err := yamlNode(resource.Content)
+	if err != nil {
+		return err
+	}
+
+	encoder := yaml.NewEncoder(w)
+	if err := encoder.Encode(node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// yamlNode builds the YAML document of a JSON content, writing its numbers exactly as they were received and its keys in order
+func yamlNode(v interface{}) (*yaml.Node, error) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, k := range keys {
+			child, err := yamlNode(value[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range value {
+			child, err := yamlNode(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(value.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value.String()}, nil
+	}
+
+	node := &yaml.Node{}
+	err := node.Encode(v)
+	return node, err
+}
+
+func cborOutMapper(w io.Writer, resource *Resource) error {
+	encoding, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		return err
+	}
+	return encoding.NewEncoder(w).Encode(binaryNumbers(resource.Content, true))
+}
+
+func msgpackOutMapper(w io.Writer, resource *Resource) error {
+	encoder := msgpack.NewEncoder(w)
+	encoder.SetSortMapKeys(true)
+	encoder.UseCompactInts(true)
+	return encoder.Encode(binaryNumbers(resource.Content, false))
+}
+
+// binaryNumbers converts the JSON numbers of a content to the numbers of the binary encodings:
+// integers are kept exactly when they fit in 64 bits, larger ones as big integers if the encoding has them
+// (CBOR does, MessagePack does not), and the other numbers become doubles.
+func binaryNumbers(v interface{}, bigIntegers bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(value))
+		for k, child := range value {
+			c[k] = binaryNumbers(child, bigIntegers)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(value))
+		for i, child := range value {
+			c[i] = binaryNumbers(child, bigIntegers)
+		}
+		return c
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(value.String(), 10, 64); err == nil {
+			return u
+		}
+		if i, ok := new(big.Int).SetString(value.String(), 10); ok && bigIntegers {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	}
+	return v
+}
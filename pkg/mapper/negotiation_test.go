@@ -0,0 +1,136 @@
+package mapper
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestNegotiateOutMapper(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		accept      string
+		expected    string
+		err         error
+	}{
+		{name: "no accept header", contentType: articleCt, accept: "", expected: articleCt},
+		{name: "any media type", contentType: articleCt, accept: "*/*", expected: articleCt},
+		{name: "stored media type", contentType: articleCt, accept: "application/vnd.ft-upp-article+json", expected: articleCt},
+		{name: "stored media type within a range", contentType: articleCt, accept: "application/*", expected: articleCt},
+		{name: "json variant accepted as json", contentType: articleCt, accept: "application/json", expected: articleCt},
+		{name: "json variant refused", contentType: articleCt, accept: "application/vnd.ft-upp-article+json;q=0, application/json", expected: "application/json"},
+		{name: "yaml", contentType: articleCt, accept: "application/yaml", expected: "application/yaml"},
+		{name: "yaml alias", contentType: articleCt, accept: "text/yaml", expected: "text/yaml"},
+		{name: "cbor", contentType: articleCt, accept: "application/cbor", expected: "application/cbor"},
+		{name: "msgpack", contentType: articleCt, accept: "application/x-msgpack", expected: "application/x-msgpack"},
+		{name: "highest quality first", contentType: articleCt, accept: "application/json;q=0.2, application/cbor;q=0.8", expected: "application/cbor"},
+		{name: "refused media type", contentType: "application/json", accept: "application/json;q=0, application/*", expected: "application/yaml"},
+		{name: "invalid ranges are ignored", contentType: articleCt, accept: "not a media type, application/yaml", expected: "application/yaml"},
+		{name: "no rendering", contentType: articleCt, accept: "text/html", err: ErrNotAcceptable},
+		{name: "binary content is not rendered", contentType: "image/png", accept: "application/json", err: ErrNotAcceptable},
+		{name: "binary content", contentType: "image/png", accept: "image/*", expected: "image/png"},
+		{name: "binary content with any media type", contentType: "image/png", accept: "*/*", expected: "image/png"},
+		{name: "invalid content type", contentType: "", accept: "*/*", err: ErrUnsupportedContentType},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			om, contentType, err := NegotiateOutMapper(test.contentType, test.accept)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, om)
+			assert.Equal(t, test.expected, contentType)
+		})
+	}
+}
+
+func TestYamlOutMapperKeepsNumbers(t *testing.T) {
+	content := map[string]interface{}{
+		"title":  "Title",
+		"id":     json.Number("9007199254740993"),
+		"price":  json.Number("3.141592653589793238462643383279"),
+		"tags":   []interface{}{"a", true, nil},
+		"nested": map[string]interface{}{"ratio": json.Number("1.50")},
+	}
+
+	var writer bytes.Buffer
+	err := yamlOutMapper(&writer, &Resource{Content: content})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `id: 9007199254740993
+nested:
+    ratio: 1.50
+price: 3.141592653589793238462643383279
+tags:
+    - a
+    - true
+    - null
+title: Title
+`, writer.String())
+}
+
+func TestBinaryOutMappers(t *testing.T) {
+	content := map[string]interface{}{
+		"title": "Title",
+		"id":    json.Number("9007199254740993"),
+		"ratio": json.Number("1.5"),
+		"tags":  []interface{}{"a", "b"},
+	}
+
+	var writer bytes.Buffer
+	err := cborOutMapper(&writer, &Resource{Content: content})
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, cbor.Unmarshal(writer.Bytes(), &decoded))
+	assert.Equal(t, uint64(9007199254740993), decoded["id"])
+	assert.Equal(t, 1.5, decoded["ratio"])
+	assert.Equal(t, "Title", decoded["title"])
+	assert.Equal(t, []interface{}{"a", "b"}, decoded["tags"])
+
+	writer.Reset()
+	err = msgpackOutMapper(&writer, &Resource{Content: content})
+	assert.NoError(t, err)
+
+	decoded = nil
+	assert.NoError(t, msgpack.Unmarshal(writer.Bytes(), &decoded))
+	assert.EqualValues(t, 9007199254740993, decoded["id"])
+	assert.Equal(t, 1.5, decoded["ratio"])
+	assert.Equal(t, "Title", decoded["title"])
+	assert.Equal(t, []interface{}{"a", "b"}, decoded["tags"])
+}
+
+func TestBinaryOutMappersLargeIntegers(t *testing.T) {
+	content := map[string]interface{}{
+		"unsigned": json.Number("18446744073709551615"),
+		"big":      json.Number("123456789012345678901234567890"),
+	}
+
+	var writer bytes.Buffer
+	err := cborOutMapper(&writer, &Resource{Content: content})
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, cbor.Unmarshal(writer.Bytes(), &decoded))
+	assert.Equal(t, uint64(18446744073709551615), decoded["unsigned"])
+	expected, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, *expected, decoded["big"])
+
+	writer.Reset()
+	err = msgpackOutMapper(&writer, &Resource{Content: content})
+	assert.NoError(t, err)
+
+	decoded = nil
+	assert.NoError(t, msgpack.Unmarshal(writer.Bytes(), &decoded))
+	assert.Equal(t, uint64(18446744073709551615), decoded["unsigned"])
+	assert.Equal(t, 1.2345678901234568e+29, decoded["big"])
+}
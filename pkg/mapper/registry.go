@@ -0,0 +1,161 @@
+package mapper
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrInvalidPattern is returned when registering mappers for a media type pattern which is not supported
+var ErrInvalidPattern = errors.New("invalid media type pattern")
+
+// mapping holds the mappers registered for a media type pattern, and whether its in mapper decodes JSON
+type mapping struct {
+	pattern     string
+	specificity int
+	in          InMapper
+	out         OutMapper
+	json        bool
+}
+
+var registry = struct {
+	sync.RWMutex
+	mappings []mapping
+}{}
+
+func init() {
+	for _, m := range []struct {
+		pattern string
+		in      InMapper
+		out     OutMapper
+		json    bool
+	}{
+		{"application/json", jsonVariantInMapper, jsonVariantOutMapper, true},
+		{"application/*+json", jsonVariantInMapper, jsonVariantOutMapper, true},
+		{"application/xml", xmlInMapper, rawOutMapper, false},
+		{"text/xml", xmlInMapper, rawOutMapper, false},
+		{"application/*+xml", xmlInMapper, rawOutMapper, false},
+		{"*/*", rawInMapper, rawOutMapper, false},
+	} {
+		if err := register(m.pattern, m.in, m.out, m.json); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Register sets the mappers of the content types matching a media type pattern, replacing the mappers previously registered for the same pattern.
+// A pattern is either a media type, e.g. application/xml, a structured syntax suffix, e.g. application/*+xml, a type, e.g. text/*, or */*.
+// The most specific pattern matching a content type is used, in that order, whatever the order of the registrations.
+// Content types are matched without their parameters and case-insensitively.
+func Register(pattern string, in InMapper, out OutMapper) error {
+	return register(pattern, in, out, false)
+}
+
+// RegisterJSON sets the mappers of the content types matching a media type pattern like Register,
+// for an in mapper decoding JSON objects, so that the content can be patched and negotiated like the built-in JSON types.
+func RegisterJSON(pattern string, in InMapper, out OutMapper) error {
+	return register(pattern, in, out, true)
+}
+
+func register(pattern string, in InMapper, out OutMapper, json bool) error {
+	if in == nil || out == nil {
+		return fmt.Errorf("%w %q: both mappers are required", ErrInvalidPattern, pattern)
+	}
+	normalised, specificity, err := parsePattern(pattern)
+	if err != nil {
+		return err
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	m := mapping{pattern: normalised, specificity: specificity, in: in, out: out, json: json}
+	for i := range registry.mappings {
+		if registry.mappings[i].pattern == normalised {
+			registry.mappings[i] = m
+			return nil
+		}
+	}
+	registry.mappings = append(registry.mappings, m)
+	sort.SliceStable(registry.mappings, func(i, j int) bool {
+		return registry.mappings[i].specificity > registry.mappings[j].specificity
+	})
+	return nil
+}
+
+// Patterns returns the media type patterns which have mappers, from the most to the least specific
+func Patterns() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	patterns := make([]string, 0, len(registry.mappings))
+	for _, m := range registry.mappings {
+		patterns = append(patterns, m.pattern)
+	}
+	return patterns
+}
+
+// lookup returns the mappers registered for the most specific pattern matching a content type
+func lookup(contentType string) (mapping, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return mapping{}, ErrUnsupportedContentType
+	}
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	for _, m := range registry.mappings {
+		if patternMatches(m.pattern, mediaType) {
+			return m, nil
+		}
+	}
+	return mapping{}, ErrUnsupportedContentType
+}
+
+// parsePattern returns a pattern in lower case with its specificity, from 3 for a media type down to 0 for */*
+func parsePattern(pattern string) (string, int, error) {
+	mediaType, params, err := mime.ParseMediaType(pattern)
+	if err != nil || len(params) > 0 {
+		return "", 0, fmt.Errorf("%w %q", ErrInvalidPattern, pattern)
+	}
+
+	mainType, subtype, found := strings.Cut(mediaType, "/")
+	switch {
+	case !found || subtype == "":
+		return "", 0, fmt.Errorf("%w %q", ErrInvalidPattern, pattern)
+	case mediaType == "*/*":
+		return mediaType, 0, nil
+	case mainType == "*":
+		return "", 0, fmt.Errorf("%w %q", ErrInvalidPattern, pattern)
+	case subtype == "*":
+		return mediaType, 1, nil
+	case strings.HasPrefix(subtype, "*+") && len(subtype) > 2 && !strings.Contains(subtype[2:], "*"):
+		return mediaType, 2, nil
+	case !strings.Contains(subtype, "*"):
+		return mediaType, 3, nil
+	}
+	return "", 0, fmt.Errorf("%w %q", ErrInvalidPattern, pattern)
+}
+
+func patternMatches(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+
+	patternType, patternSubtype, _ := strings.Cut(pattern, "/")
+	mainType, subtype, _ := strings.Cut(mediaType, "/")
+	if patternType != mainType {
+		return false
+	}
+	if patternSubtype == "*" {
+		return true
+	}
+	if suffix, found := strings.CutPrefix(patternSubtype, "*"); found {
+		return strings.HasSuffix(subtype, suffix) && len(subtype) > len(suffix)
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package mapper
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var defaultPatterns = []string{"application/json", "application/xml", "text/xml", "application/*+json", "application/*+xml", "*/*"}
+
+// restoreRegistry puts back the mappers registered before a test
+func restoreRegistry(t *testing.T) {
+	registry.RLock()
+	saved := append([]mapping(nil), registry.mappings...)
+	registry.RUnlock()
+
+	t.Cleanup(func() {
+		registry.Lock()
+		registry.mappings = saved
+		registry.Unlock()
+	})
+}
+
+func upperInMapper(r io.ReadCloser) (interface{}, error) {
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	return strings.ToUpper(string(data)), err
+}
+
+func upperOutMapper(w io.Writer, resource *Resource) error {
+	_, err := io.WriteString(w, resource.Content.(string))
+	return err
+}
+
+func TestRegisterPrecedence(t *testing.T) {
+	restoreRegistry(t)
+
+	assert.NoError(t, Register("text/*", upperInMapper, upperOutMapper))
+	assert.NoError(t, Register("application/*+yaml", upperInMapper, upperOutMapper))
+	assert.NoError(t, Register("application/vnd.ft-upp-article+json", upperInMapper, upperOutMapper))
+
+	tests := []struct {
+		contentType string
+		upper       bool
+	}{
+		{contentType: textPlainCt, upper: true},
+		{contentType: "Text/HTML", upper: true},
+		{contentType: "application/vnd.feed+yaml; charset=utf-8", upper: true},
+		{contentType: "application/yaml", upper: false},
+		{contentType: articleCt, upper: true},
+		{contentType: "application/vnd.ft-upp-list+json", upper: false},
+		{contentType: "image/png", upper: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.contentType, func(t *testing.T) {
+			inMapper, err := InMapperForContentType(test.contentType)
+			assert.NoError(t, err)
+
+			content, err := inMapper(io.NopCloser(strings.NewReader(`{"id":"x"}`)))
+			assert.NoError(t, err)
+			_, isUpper := content.(string)
+			assert.Equal(t, test.upper, isUpper)
+		})
+	}
+
+	assert.Equal(t, []string{"application/json", "application/xml", "text/xml", "application/vnd.ft-upp-article+json", "application/*+json", "application/*+xml", "application/*+yaml", "text/*", "*/*"}, Patterns())
+}
+
+func TestRegisterReplacesMappers(t *testing.T) {
+	restoreRegistry(t)
+
+	assert.NoError(t, Register("Application/JSON", upperInMapper, upperOutMapper))
+	assert.Equal(t, defaultPatterns, Patterns())
+
+	outMapper, err := OutMapperForContentType("application/json; charset=utf-8")
+	assert.NoError(t, err)
+
+	var writer bytes.Buffer
+	assert.NoError(t, outMapper(&writer, &Resource{Content: "ID"}))
+	assert.Equal(t, "ID", writer.String())
+}
+
+func TestRegisterInvalidPatterns(t *testing.T) {
+	restoreRegistry(t)
+
+	for _, pattern := range []string{"", "application", "*/json", "application/x*", "application/*+", "application/*+*", "text/*; charset=utf-8"} {
+		assert.ErrorIs(t, Register(pattern, upperInMapper, upperOutMapper), ErrInvalidPattern, pattern)
+	}
+	assert.ErrorIs(t, Register("text/*", nil, upperOutMapper), ErrInvalidPattern)
+	assert.Equal(t, defaultPatterns, Patterns())
+}
+
+func TestIsJSONFollowsRegistrations(t *testing.T) {
+	restoreRegistry(t)
+
+	assert.True(t, IsJSON("Application/JSON"))
+	assert.True(t, IsJSON("application/vnd.ft-upp-article+json; charset=utf-8"))
+	assert.False(t, IsJSON("text/json"))
+	assert.False(t, IsJSON("not a media type"))
+
+	assert.NoError(t, RegisterJSON("text/json", jsonVariantInMapper, jsonVariantOutMapper))
+	assert.NoError(t, Register("application/vnd.ft-upp-list+json", upperInMapper, upperOutMapper))
+
+	assert.True(t, IsJSON("text/json"))
+	assert.False(t, IsJSON("application/vnd.ft-upp-list+json"))
+	assert.True(t, IsJSON(articleCt))
+}
@@ -0,0 +1,82 @@
+package mapper
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrInvalidXML is returned when an XML content is not well-formed
+var ErrInvalidXML = errors.New("invalid XML content")
+
+// xmlInMapper keeps an XML content as the raw bytes received, so that its namespaces, attributes, ordering and formatting are served back unchanged,
+// after checking that it is a well-formed document.
+func xmlInMapper(r io.ReadCloser) (interface{}, error) {
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkXML(data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// checkXML checks that an XML document is well-formed and has a single root element
+func checkXML(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = singleByteCharsetReader
+
+	roots := 0
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidXML, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return fmt.Errorf("%w: text outside of the root element", ErrInvalidXML)
+			}
+		}
+	}
+
+	if roots != 1 {
+		return fmt.Errorf("%w: %d root elements", ErrInvalidXML, roots)
+	}
+	return nil
+}
+
+// singleByteCharsetReader reads the documents declaring an encoding other than UTF-8 one byte per character, which is enough to check their structure.
+// The content itself is stored as received.
+func singleByteCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	if strings.EqualFold(charset, "utf-8") {
+		return input, nil
+	}
+
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	var decoded strings.Builder
+	for _, b := range data {
+		decoded.WriteRune(rune(b))
+	}
+	return strings.NewReader(decoded.String()), nil
+}
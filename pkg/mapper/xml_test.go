@@ -0,0 +1,59 @@
+package mapper
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXMLMappersRoundTrip(t *testing.T) {
+	documents := map[string][]byte{
+		"namespaces and attributes": []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:ft="urn:ft">
+	<entry z="1" a="2"><ft:title>Title &amp; more</ft:title><![CDATA[<raw>]]></entry>
+	<!-- comment -->
+</feed>
+`),
+		"single byte encoding": append([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><doc>caf`), 0xe9, '<', '/', 'd', 'o', 'c', '>'),
+		"doctype":              []byte(`<!DOCTYPE nitf SYSTEM "nitf-3-5.dtd"><nitf><body/></nitf>`),
+	}
+
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			inMapper, err := InMapperForContentType("application/atom+xml; charset=utf-8")
+			assert.NoError(t, err)
+
+			content, err := inMapper(io.NopCloser(bytes.NewReader(document)))
+			assert.NoError(t, err)
+
+			var writer bytes.Buffer
+			outMapper, err := OutMapperForContentType("application/atom+xml; charset=utf-8")
+			assert.NoError(t, err)
+			assert.NoError(t, outMapper(&writer, &Resource{Content: content}))
+			assert.Equal(t, document, writer.Bytes())
+		})
+	}
+}
+
+func TestXMLInMapperRejectsMalformedDocuments(t *testing.T) {
+	documents := map[string]string{
+		"empty":            ``,
+		"not XML":          `{"id":"x"}`,
+		"unclosed element": `<doc><p></doc>`,
+		"two roots":        `<doc/><doc/>`,
+		"trailing text":    `<doc/>text`,
+		"invalid UTF-8":    "<doc>caf\xe9</doc>",
+	}
+
+	for name, document := range documents {
+		t.Run(name, func(t *testing.T) {
+			inMapper, err := InMapperForContentType("text/xml")
+			assert.NoError(t, err)
+
+			_, err = inMapper(io.NopCloser(bytes.NewReader([]byte(document))))
+			assert.ErrorIs(t, err, ErrInvalidXML)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+const purgeBatchSize = 500
+
+// bulkPurgeRequest selects the revisions to purge. The time range accepts the same formats as ?asOf=.
+type bulkPurgeRequest struct {
+	From           string   `json:"from,omitempty"`
+	To             string   `json:"to,omitempty"`
+	OriginSystemID string   `json:"origin-system-id,omitempty"`
+	SchemaVersion  string   `json:"schema-version,omitempty"`
+	UUIDs          []string `json:"uuids,omitempty"`
+}
+
+// bulkPurgeProgress is streamed after every batch, and once more when the purge ends
+type bulkPurgeProgress struct {
+	Deleted int64  `json:"deleted"`
+	Total   int64  `json:"total"`
+	Done    bool   `json:"done,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func (p bulkPurgeRequest) revisionFilter() (db.RevisionFilter, error) {
+	from, err := parseAsOf(p.From)
+	if err != nil {
+		return db.RevisionFilter{}, fmt.Errorf("invalid from: %w", err)
+	}
+	to, err := parseAsOf(p.To)
+	if err != nil {
+		return db.RevisionFilter{}, fmt.Errorf("invalid to: %w", err)
+	}
+	if from > 0 && to > 0 && from >= to {
+		return db.RevisionFilter{}, errors.New("from must be before to")
+	}
+	for _, id := range p.UUIDs {
+		if !uuidRegexp.MatchString(id) {
+			return db.RevisionFilter{}, fmt.Errorf("invalid uuid %v", id)
+		}
+	}
+
+	filter := db.RevisionFilter{
+		From:           from,
+		To:             to,
+		OriginSystemID: p.OriginSystemID,
+		SchemaVersion:  p.SchemaVersion,
+		UUIDs:          p.UUIDs,
+	}
+	if filter.IsEmpty() {
+		return filter, errors.New("the filter matches the whole collection")
+	}
+	return filter, nil
+}
+
+// BulkPurgeContent deletes the revisions matching a filter from the given collection in batches,
+// streaming the progress as newline delimited JSON. It stops when the client goes away, and records every batch in the audit trail along with its deletion.
+func BulkPurgeContent(connection db.Connection, ts TimestampCreator) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+		collectionID := mux.Vars(r)["collection"]
+		requestedBy := r.Header.Get(RequestedByHeader)
+
+		var purgeRequest bulkPurgeRequest
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		err := decoder.Decode(&purgeRequest)
+		if err != nil {
+			msg := "Extracting the purge filter from HTTP body failed"
+			logger.WithTransactionID(tid).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		filter, err := purgeRequest.revisionFilter()
+		if err != nil {
+			msg := "Invalid purge filter"
+			logger.WithTransactionID(tid).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		flusher, _ := w.(http.Flusher)
+
+		progress := func(p bulkPurgeProgress) {
+			if err := encoder.Encode(p); err != nil {
+				logger.WithTransactionID(tid).WithError(err).Error("unable to write purge progress")
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		filterJSON, _ := json.Marshal(purgeRequest)
+		audit := db.AuditEntry{
+			Action:        "bulk-purge",
+			Collection:    collectionID,
+			Filter:        string(filterJSON),
+			RequestedBy:   requestedBy,
+			TransactionID: tid,
+			Time:          time.Unix(0, ts.CreateTimestamp()).UTC(),
+		}
+
+		ctx := r.Context()
+		var total int64
+		for {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+
+			var deleted int64
+			deleted, err = connection.DeleteMatching(ctx, collectionID, filter, purgeBatchSize, audit)
+			if err != nil || deleted == 0 {
+				break
+			}
+
+			total += deleted
+			progress(bulkPurgeProgress{Deleted: deleted, Total: total})
+		}
+
+		if err != nil {
+			msg := "Purging from mongoDB failed"
+			logger.WithMonitoringEvent("SaveToNative", tid, "").WithField("collection", collectionID).WithField("revisions", total).WithError(err).Error(msg)
+			progress(bulkPurgeProgress{Total: total, Error: fmt.Sprintf("%s: %v", msg, err)})
+			return
+		}
+
+		logger.WithMonitoringEvent("SaveToNative", tid, "").
+			WithField("collection", collectionID).
+			WithField("requested-by", requestedBy).
+			WithField("filter", string(filterJSON)).
+			WithField("revisions", total).
+			Info("Successfully purged matching revisions")
+		progress(bulkPurgeProgress{Total: total, Done: true})
+	}
+}
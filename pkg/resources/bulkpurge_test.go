@@ -0,0 +1,126 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+)
+
+func TestBulkPurgeContent(t *testing.T) {
+	filter := db.RevisionFilter{
+		From:           1672531200000000000,
+		To:             1672617600000000000,
+		OriginSystemID: "load-test",
+		UUIDs:          []string{"a0c2a8f2-1c3a-4e4b-8d5a-9e8b7c6d5e4f"},
+	}
+
+	audit := db.AuditEntry{
+		Action:        "bulk-purge",
+		Collection:    "universal-content",
+		Filter:        `{"from":"2023-01-01T00:00:00Z","to":"1672617600000000000","origin-system-id":"load-test","uuids":["a0c2a8f2-1c3a-4e4b-8d5a-9e8b7c6d5e4f"]}`,
+		RequestedBy:   "ops@example.com",
+		TransactionID: "tid_bulk",
+		Time:          time.Unix(0, 1436773875771421417).UTC(),
+	}
+
+	connection := new(MockConnection)
+	connection.On("DeleteMatching", mock.Anything, "universal-content", filter, int64(500), audit).Return(int64(500), nil).Once()
+	connection.On("DeleteMatching", mock.Anything, "universal-content", filter, int64(500), audit).Return(int64(20), nil).Once()
+	connection.On("DeleteMatching", mock.Anything, "universal-content", filter, int64(500), audit).Return(int64(0), nil).Once()
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/__purge", BulkPurgeContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/__purge", strings.NewReader(
+		`{"from": "2023-01-01T00:00:00Z", "to": "1672617600000000000", "origin-system-id": "load-test", "uuids": ["a0c2a8f2-1c3a-4e4b-8d5a-9e8b7c6d5e4f"]}`))
+	req.Header.Add(RequestedByHeader, "ops@example.com")
+	req.Header.Add("X-Request-Id", "tid_bulk")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"deleted":500,"total":500}
+{"deleted":20,"total":520}
+{"deleted":0,"total":520,"done":true}
+`, w.Body.String())
+}
+
+func TestBulkPurgeContentFails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("DeleteMatching", mock.Anything, "universal-content", db.RevisionFilter{SchemaVersion: "1"}, int64(500), mock.Anything).Return(int64(500), nil).Once()
+	connection.On("DeleteMatching", mock.Anything, "universal-content", db.RevisionFilter{SchemaVersion: "1"}, int64(500), mock.Anything).Return(int64(0), errors.New("i failed")).Once()
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/__purge", BulkPurgeContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/__purge", strings.NewReader(`{"schema-version": "1"}`))
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"deleted":500,"total":500}
+{"deleted":0,"total":500,"error":"Purging from mongoDB failed: i failed"}
+`, w.Body.String())
+}
+
+func TestBulkPurgeContentCancelled(t *testing.T) {
+	connection := new(MockConnection)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/__purge", BulkPurgeContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequestWithContext(ctx, "POST", "/universal-content/__purge", strings.NewReader(`{"schema-version": "1"}`))
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "DeleteMatching", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Contains(t, w.Body.String(), "context canceled")
+}
+
+func TestBulkPurgeContentInvalidFilter(t *testing.T) {
+	for _, body := range []string{
+		`{}`,
+		`{"from": "yesterday"}`,
+		`{"from": "2023-01-02T00:00:00Z", "to": "2023-01-01T00:00:00Z"}`,
+		`{"from": "1672531200000000000", "to": "2023-01-01T00:00:00Z"}`,
+		`{"uuids": ["not-a-uuid"]}`,
+		`{"origin": "load-test"}`,
+		`not json`,
+	} {
+		connection := new(MockConnection)
+
+		ts := fixedTimestampCreator{}
+
+		router := mux.NewRouter()
+		router.HandleFunc("/{collection}/__purge", BulkPurgeContent(connection, &ts)).Methods("POST")
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/universal-content/__purge", strings.NewReader(body))
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
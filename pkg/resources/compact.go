@@ -0,0 +1,69 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/maintenance"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// compactionProgress is streamed every few hundred documents, and once more when the compaction ends
+type compactionProgress struct {
+	maintenance.CompactionResults
+	DryRun bool   `json:"dryRun,omitempty"`
+	Done   bool   `json:"done,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CompactContent removes the revisions identical to the revision before them from every document of the given collection,
+// streaming the progress as newline delimited JSON. It stops when the client goes away.
+// With ?dryRun=true it only counts the duplicate revisions.
+func CompactContent(connection db.Connection) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+		collectionID := mux.Vars(r)["collection"]
+		dryRun := isDryRun(r)
+
+		w.Header().Add("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		encoder := json.NewEncoder(w)
+		flusher, _ := w.(http.Flusher)
+
+		progress := func(p compactionProgress) {
+			p.DryRun = dryRun
+			if err := encoder.Encode(p); err != nil {
+				logger.WithTransactionID(tid).WithError(err).Error("unable to write compaction progress")
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+
+		results, err := maintenance.Compact(r.Context(), connection, collectionID, dryRun, func(results maintenance.CompactionResults) {
+			progress(compactionProgress{CompactionResults: results})
+		})
+		if err != nil {
+			msg := "Compacting the revisions failed"
+			logger.WithMonitoringEvent("SaveToNative", tid, "").WithField("collection", collectionID).WithError(err).Error(msg)
+			progress(compactionProgress{CompactionResults: results, Error: fmt.Sprintf("%s: %v", msg, err)})
+			return
+		}
+
+		logger.WithMonitoringEvent("SaveToNative", tid, "").
+			WithField("collection", collectionID).
+			WithField("dryRun", dryRun).
+			WithField("documents", results.Documents).
+			WithField("duplicates", results.Duplicates).
+			WithField("reclaimed", results.Reclaimed).
+			Info("Successfully compacted revisions")
+		progress(compactionProgress{CompactionResults: results, Done: true})
+	}
+}
@@ -0,0 +1,62 @@
+package resources
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func TestCompactContent(t *testing.T) {
+	revision := func(contentRevision int64) db.StoredRevision {
+		return db.StoredRevision{
+			Resource: mapper.Wrap(map[string]interface{}{"title": "a"}, "a", "application/json", "methode", "1", contentRevision),
+			Size:     64,
+		}
+	}
+	ids := make(chan string, 1)
+	ids <- "a"
+	close(ids)
+
+	connection := new(MockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(ids, nil)
+	connection.On("ReadStoredRevisions", mock.Anything, "universal-content", "a").Return([]db.StoredRevision{revision(1), revision(2), revision(3), revision(4)}, nil)
+	connection.On("DeleteRevisions", mock.Anything, "universal-content", "a", []int64{2, 3}).Return(int64(2), nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/__compact", CompactContent(connection)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/__compact", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"documents":1,"duplicateRevisions":2,"removedRevisions":2,"reclaimedBytes":128,"done":true}
+`, w.Body.String())
+}
+
+func TestCompactContentDryRunFails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(make(chan string), errors.New("i failed"))
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/__compact", CompactContent(connection)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/__compact?dryRun=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"documents":0,"duplicateRevisions":0,"removedRevisions":0,"reclaimedBytes":0,"dryRun":true,"error":"Compacting the revisions failed: reading the documents of universal-content: i failed"}
+`, w.Body.String())
+}
@@ -0,0 +1,331 @@
+package resources
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// DefaultCompressionThreshold is the size from which responses are compressed when no threshold is configured.
+// Smaller responses fit in a packet or two, and gain less than the cost of compressing them.
+const DefaultCompressionThreshold = 1024
+
+// compressionMetrics are published on /debug/vars. The ratios are the compressed size divided by the decoded size of the bodies seen since startup.
+var compressionMetrics = expvar.NewMap("compression")
+
+func init() {
+	compressionMetrics.Set("requestRatio", expvar.Func(func() interface{} {
+		return compressionRatio("requestBytes", "decodedRequestBytes")
+	}))
+	compressionMetrics.Set("responseRatio", expvar.Func(func() interface{} {
+		return compressionRatio("compressedResponseBytes", "responseBytes")
+	}))
+}
+
+func compressionRatio(compressedKey, decodedKey string) float64 {
+	compressed, _ := compressionMetrics.Get(compressedKey).(*expvar.Int)
+	decoded, _ := compressionMetrics.Get(decodedKey).(*expvar.Int)
+	if compressed == nil || decoded == nil || decoded.Value() == 0 {
+		return 0
+	}
+	return float64(compressed.Value()) / float64(decoded.Value())
+}
+
+// DecodeContent decompresses the request bodies sent with Content-Encoding: gzip or deflate, and rejects the other encodings with 415 Unsupported Media Type.
+// It has to run before LimitBodySize, so that the limit applies to the decoded body.
+func (f *Filters) DecodeContent() *Filters {
+	next := f.next
+	f.next = func(w http.ResponseWriter, r *http.Request) {
+		encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" {
+			next(w, r)
+			return
+		}
+
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+		received := &countingReader{reader: r.Body}
+		var decoder io.ReadCloser
+		var err error
+		switch encoding {
+		case "gzip", "x-gzip":
+			decoder, err = gzip.NewReader(received)
+		case "deflate":
+			decoder, err = zlib.NewReader(received)
+		default:
+			defer r.Body.Close()
+
+			msg := fmt.Sprintf("Unsupported content-encoding %v, the request body must be sent with gzip, deflate or no encoding", encoding)
+			logger.WithTransactionID(tid).Error(msg)
+			w.Header().Set("Accept-Encoding", "gzip, deflate")
+			http.Error(w, msg, http.StatusUnsupportedMediaType)
+			return
+		}
+		if err != nil {
+			defer r.Body.Close()
+
+			msg := fmt.Sprintf("Unable to decode the %v request body", encoding)
+			logger.WithTransactionID(tid).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		decoded := &countingReader{reader: decoder}
+		r.Body = &decodedBody{Reader: decoded, decoder: decoder, body: r.Body}
+		r.Header.Del("Content-Encoding")
+		r.ContentLength = -1
+
+		next(w, r)
+
+		compressionMetrics.Add("decodedRequests", 1)
+		compressionMetrics.Add("requestBytes", received.n)
+		compressionMetrics.Add("decodedRequestBytes", decoded.n)
+	}
+	return f
+}
+
+// CompressResponse compresses the responses with gzip or deflate when the client accepts them in Accept-Encoding, and the body reaches threshold bytes.
+// Successful responses are held back until the threshold is reached, including streamed ones, and are sent uncompressed when they are smaller.
+func (f *Filters) CompressResponse(threshold int) *Filters {
+	next := f.next
+	f.next = func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		cw := &compressingWriter{ResponseWriter: w, encoding: encoding, threshold: threshold, ifNoneMatch: r.Header.Get(IfNoneMatchHeader)}
+		next(cw, r)
+		if err := cw.close(); err != nil {
+			logger.WithTransactionID(transactionidutils.GetTransactionIDFromRequest(r)).WithError(err).Error("could not write the compressed response")
+		}
+	}
+	return f
+}
+
+// negotiateEncoding returns the content coding preferred by the client among gzip and deflate, or an empty string if it accepts neither.
+// gzip is chosen when both have the same quality.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := map[string]float64{}
+	for _, value := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(value), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		quality := 1.0
+		if q, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			var err error
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if coding == "x-gzip" {
+			coding = "gzip"
+		}
+		qualities[coding] = quality
+	}
+
+	best, bestQuality := "", 0.0
+	for _, coding := range []string{"gzip", "deflate"} {
+		quality, found := qualities[coding]
+		if !found {
+			quality, found = qualities["*"]
+		}
+		if found && quality > bestQuality {
+			best, bestQuality = coding, quality
+		}
+	}
+	return best
+}
+
+// compressingWriter buffers a response until it reaches the threshold, and then compresses it.
+// Responses with another status than 200 OK, or already encoded, are written unchanged.
+// A compressed response is another representation than the identity one, so its strong ETag gets the content coding as a suffix, e.g. "1436773875771421417+gzip".
+type compressingWriter struct {
+	http.ResponseWriter
+	encoding    string
+	threshold   int
+	ifNoneMatch string
+
+	status      int
+	passthrough bool
+	buffer      []byte
+	encoder     io.WriteCloser
+	sent        *countingWriter
+	written     int64
+}
+
+func (cw *compressingWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	if status == http.StatusNotModified {
+		cw.matchEncodedETag()
+	}
+	if status != http.StatusOK || cw.Header().Get("Content-Encoding") != "" {
+		cw.passthrough = true
+		cw.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (cw *compressingWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passthrough {
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.written += int64(len(p))
+	if cw.encoder != nil {
+		return cw.encoder.Write(p)
+	}
+
+	cw.buffer = append(cw.buffer, p...)
+	if len(cw.buffer) < cw.threshold {
+		return len(p), nil
+	}
+	if err := cw.startCompression(); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been written so far, unless the response is still held back below the threshold
+func (cw *compressingWriter) Flush() {
+	if cw.encoder != nil {
+		if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+			if err := f.Flush(); err != nil {
+				return
+			}
+		}
+	} else if !cw.passthrough {
+		return
+	}
+
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (cw *compressingWriter) startCompression() error {
+	cw.Header().Set("Content-Encoding", cw.encoding)
+	cw.Header().Del("Content-Length")
+	if etag := cw.Header().Get(ETagHeader); etag != "" {
+		cw.Header().Set(ETagHeader, encodedETag(etag, cw.encoding))
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	cw.sent = &countingWriter{writer: cw.ResponseWriter}
+	if cw.encoding == "gzip" {
+		cw.encoder = gzip.NewWriter(cw.sent)
+	} else {
+		cw.encoder = zlib.NewWriter(cw.sent)
+	}
+
+	buffered := cw.buffer
+	cw.buffer = nil
+	_, err := cw.encoder.Write(buffered)
+	return err
+}
+
+// matchEncodedETag answers a client holding the compressed representation with its ETag, so that the 304 refreshes the representation it has cached
+func (cw *compressingWriter) matchEncodedETag() {
+	etag := cw.Header().Get(ETagHeader)
+	if etag == "" {
+		return
+	}
+	encoded := encodedETag(etag, cw.encoding)
+	for _, tag := range strings.Split(cw.ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == encoded {
+			cw.Header().Set(ETagHeader, encoded)
+			return
+		}
+	}
+}
+
+// encodedETag adds a content coding to a strong entity tag. Weak tags are left unchanged, as they can be shared by the encodings of a representation.
+func encodedETag(etag, encoding string) string {
+	if strings.HasPrefix(etag, "W/") || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "+" + encoding + `"`
+}
+
+// withoutContentCoding removes the content coding added to an entity tag by encodedETag
+func withoutContentCoding(tag string) string {
+	for _, encoding := range []string{"gzip", "deflate"} {
+		if trimmed, found := strings.CutSuffix(tag, "+"+encoding+`"`); found {
+			return trimmed + `"`
+		}
+	}
+	return tag
+}
+
+// close finishes the compressed stream, or sends the response held back uncompressed when it is smaller than the threshold
+func (cw *compressingWriter) close() error {
+	if cw.encoder != nil {
+		err := cw.encoder.Close()
+		compressionMetrics.Add("compressedResponses", 1)
+		compressionMetrics.Add("responseBytes", cw.written)
+		compressionMetrics.Add("compressedResponseBytes", cw.sent.n)
+		return err
+	}
+	if cw.passthrough || cw.status == 0 {
+		return nil
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+	_, err := cw.ResponseWriter.Write(cw.buffer)
+	return err
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+type countingWriter struct {
+	writer io.Writer
+	n      int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.writer.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// decodedBody closes both the decoder and the original request body
+type decodedBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	err := b.decoder.Close()
+	if bodyErr := b.body.Close(); bodyErr != nil {
+		return bodyErr
+	}
+	return err
+}
@@ -0,0 +1,278 @@
+package resources
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func gzipped(t *testing.T, data string) []byte {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return compressed.Bytes()
+}
+
+func TestDecodeContent(t *testing.T) {
+	var deflated bytes.Buffer
+	writer := zlib.NewWriter(&deflated)
+	_, _ = writer.Write([]byte(`{"title":"Title"}`))
+	_ = writer.Close()
+
+	tests := map[string][]byte{
+		"gzip":     gzipped(t, `{"title":"Title"}`),
+		"deflate":  deflated.Bytes(),
+		"identity": []byte(`{"title":"Title"}`),
+	}
+
+	for encoding, body := range tests {
+		t.Run(encoding, func(t *testing.T) {
+			var received string
+			next := func(w http.ResponseWriter, r *http.Request) {
+				data, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				received = string(data)
+				if encoding != "identity" {
+					assert.Empty(t, r.Header.Get("Content-Encoding"))
+				}
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/{collection}/{resource}", Filter(next).DecodeContent().Build()).Methods("POST")
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", bytes.NewReader(body))
+			req.Header.Add("Content-Encoding", encoding)
+
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, `{"title":"Title"}`, received)
+		})
+	}
+}
+
+func TestDecodeContentFailures(t *testing.T) {
+	tests := map[string]struct {
+		encoding string
+		status   int
+	}{
+		"unsupported encoding": {encoding: "br", status: http.StatusUnsupportedMediaType},
+		"invalid gzip body":    {encoding: "gzip", status: http.StatusBadRequest},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			forwarded := false
+			next := func(w http.ResponseWriter, r *http.Request) {
+				forwarded = true
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/{collection}/{resource}", Filter(next).DecodeContent().Build()).Methods("POST")
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{"title":"Title"}`))
+			req.Header.Add("Content-Encoding", test.encoding)
+
+			router.ServeHTTP(w, req)
+			assert.False(t, forwarded)
+			assert.Equal(t, test.status, w.Code)
+		})
+	}
+}
+
+func TestDecodeContentLimitsDecodedSize(t *testing.T) {
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(WriteContent(connection, &fixedTimestampCreator{}, nil)).LimitBodySize(64).DecodeContent().Build()).Methods("POST")
+
+	body := gzipped(t, `{"title":"`+strings.Repeat("a", 1000)+`"}`)
+	assert.Less(t, len(body), 64)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", bytes.NewReader(body))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Encoding", "gzip")
+
+	router.ServeHTTP(w, req)
+	connection.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestWriteGzipContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Write",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{"title": "Title"},
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417})).
+		Return(nil)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(WriteContent(connection, &fixedTimestampCreator{}, nil)).LimitBodySize(1024).DecodeContent().Build()).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", bytes.NewReader(gzipped(t, `{"title":"Title"}`)))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Content-Encoding", "gzip")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCompressResponse(t *testing.T) {
+	large := `{"title":"` + strings.Repeat("a", 2000) + `"}`
+
+	tests := map[string]struct {
+		acceptEncoding string
+		body           string
+		status         int
+		encoding       string
+	}{
+		"gzip":                      {acceptEncoding: "gzip, deflate", body: large, status: http.StatusOK, encoding: "gzip"},
+		"deflate":                   {acceptEncoding: "gzip;q=0.5, deflate", body: large, status: http.StatusOK, encoding: "deflate"},
+		"any encoding":              {acceptEncoding: "*", body: large, status: http.StatusOK, encoding: "gzip"},
+		"refused encoding":          {acceptEncoding: "gzip;q=0", body: large, status: http.StatusOK},
+		"no accept-encoding":        {body: large, status: http.StatusOK},
+		"below threshold":           {acceptEncoding: "gzip", body: `{"title":"Title"}`, status: http.StatusOK},
+		"unsuccessful response":     {acceptEncoding: "gzip", body: large, status: http.StatusNotFound},
+		"unsupported encoding only": {acceptEncoding: "br", body: large, status: http.StatusOK},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			next := func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "application/json")
+				w.WriteHeader(test.status)
+				_, _ = w.Write([]byte(test.body))
+			}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/{collection}/{resource}", Filter(next).CompressResponse(1024).Build()).Methods("GET")
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+			if test.acceptEncoding != "" {
+				req.Header.Add("Accept-Encoding", test.acceptEncoding)
+			}
+
+			router.ServeHTTP(w, req)
+			assert.Equal(t, test.status, w.Code)
+			assert.Equal(t, test.encoding, w.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+
+			var body io.Reader = w.Body
+			switch test.encoding {
+			case "gzip":
+				body, _ = gzip.NewReader(w.Body)
+			case "deflate":
+				body, _ = zlib.NewReader(w.Body)
+			}
+			data, err := io.ReadAll(body)
+			assert.NoError(t, err)
+			assert.Equal(t, test.body, string(data))
+		})
+	}
+}
+
+func TestCompressStreamedIDs(t *testing.T) {
+	ids := make(chan string, 100)
+	for i := 0; i < 100; i++ {
+		ids <- "d0dd3a0a-4f4b-11e5-8d3e-000000000000"
+	}
+	close(ids)
+
+	connection := new(MockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", false).Return(ids, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/__ids", Filter(ReadIDs(connection)).CompressResponse(1024).Build()).Methods("GET")
+
+	responseBytes := compressionMetrics.Get("responseBytes")
+	before := int64(0)
+	if responseBytes != nil {
+		before = responseBytes.(*expvar.Int).Value()
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/__ids", http.NoBody)
+	req.Header.Add("Accept-Encoding", "gzip")
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Len(t, lines, 100)
+	assert.Equal(t, `{"id":"d0dd3a0a-4f4b-11e5-8d3e-000000000000"}`, lines[0])
+
+	assert.Equal(t, before+int64(len(data)), compressionMetrics.Get("responseBytes").(*expvar.Int).Value())
+	var ratio float64
+	assert.NoError(t, json.Unmarshal([]byte(compressionMetrics.Get("responseRatio").String()), &ratio))
+	assert.Greater(t, ratio, 0.0)
+	assert.Less(t, ratio, 1.0)
+}
+
+func TestCompressedResponseETag(t *testing.T) {
+	resource := hashed(&mapper.Resource{
+		ContentType:     "application/json",
+		Content:         map[string]interface{}{"title": strings.Repeat("a", 2000)},
+		ContentRevision: 1436773875771421417})
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(resource, true, nil)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", ContentRevision: 1436773875771421417, ContentHash: resource.ContentHash}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(ReadContent(connection)).CompressResponse(1024).Build()).Methods("GET")
+
+	etags := map[string]string{}
+	for _, encoding := range []string{"gzip", "identity"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+		req.Header.Add("Accept-Encoding", encoding)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etags[encoding] = w.Header().Get("ETag")
+	}
+	assert.Equal(t, `"`+resource.ContentHash+`+gzip"`, etags["gzip"])
+	assert.Equal(t, `"`+resource.ContentHash+`"`, etags["identity"])
+
+	for encoding, etag := range etags {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+		req.Header.Add("Accept-Encoding", encoding)
+		req.Header.Add("If-None-Match", etag)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code, encoding)
+		assert.Equal(t, etag, w.Header().Get("ETag"), encoding)
+	}
+}
@@ -0,0 +1,178 @@
+package resources
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+const (
+	ETagHeader            = "ETag"
+	IfMatchHeader         = "If-Match"
+	IfNoneMatchHeader     = "If-None-Match"
+	LastModifiedHeader    = "Last-Modified"
+	IfModifiedSinceHeader = "If-Modified-Since"
+)
+
+// formatETag builds an entity tag from the opaque tag of a stored version of a native document.
+// The representations rendered in another content type than the stored one add their name, e.g. "<content hash>+cbor".
+func formatETag(tag string, representation string) string {
+	if representation != "" {
+		return `"` + tag + "+" + representation + `"`
+	}
+	return `"` + tag + `"`
+}
+
+// entityTag returns the opaque tag of a stored version of a native document: its content hash, so that content republished unchanged keeps its ETag.
+// Tombstones and the revisions written before hashes were stored have none, and are tagged with their content revision instead.
+func entityTag(resource *mapper.Resource) string {
+	if resource.ContentHash != "" {
+		return resource.ContentHash
+	}
+	return strconv.FormatInt(resource.ContentRevision, 10)
+}
+
+// isContentHash checks whether an entity tag is a content hash, a hex encoded SHA-224, rather than a content revision
+func isContentHash(tag string) bool {
+	if len(tag) != sha256.Size224*2 {
+		return false
+	}
+	_, err := hex.DecodeString(tag)
+	return err == nil
+}
+
+// representationOf names the rendering of a content served in another content type than the one it is stored with,
+// after the subtype of the served type, and is empty for the stored content type
+func representationOf(storedType, servedType string) string {
+	if servedType == storedType {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(servedType)
+	_, subtype, _ := strings.Cut(mediaType, "/")
+	return subtype
+}
+
+// negotiatedRepresentation returns the representation a read with the given Accept header is answered with, and false if there is none
+func negotiatedRepresentation(storedType, accept string) (string, bool) {
+	_, servedType, err := mapper.NegotiateOutMapper(storedType, accept)
+	if err != nil {
+		return "", false
+	}
+	return representationOf(storedType, servedType), true
+}
+
+// lastModified converts a content revision, which is the UTC nanosecond timestamp of the write, into its time
+func lastModified(revision int64) time.Time {
+	return time.Unix(0, revision).UTC()
+}
+
+// setRevisionHeaders adds the cache validators of the given revision to the response
+func setRevisionHeaders(w http.ResponseWriter, resource *mapper.Resource) {
+	setRepresentationHeaders(w, resource, "")
+}
+
+// setRepresentationHeaders adds the cache validators of the given representation of a revision to the response
+func setRepresentationHeaders(w http.ResponseWriter, resource *mapper.Resource, representation string) {
+	w.Header().Set(ETagHeader, formatETag(entityTag(resource), representation))
+	w.Header().Set(LastModifiedHeader, lastModified(resource.ContentRevision).Format(http.TimeFormat))
+}
+
+// isConditionalRead checks whether the client asked to only get the content if it has changed
+func isConditionalRead(r *http.Request) bool {
+	return r.Header.Get(IfNoneMatchHeader) != "" || r.Header.Get(IfModifiedSinceHeader) != ""
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when there is no If-None-Match, against the given representation of a revision.
+// Entity tags are compared weakly, as they are for reads, and whatever the content coding the representation was served with.
+func notModified(r *http.Request, resource *mapper.Resource, representation string) bool {
+	if ifNoneMatch := r.Header.Get(IfNoneMatchHeader); ifNoneMatch != "" {
+		etag := formatETag(entityTag(resource), representation)
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = withoutContentCoding(strings.TrimPrefix(strings.TrimSpace(tag), "W/"))
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get(IfModifiedSinceHeader))
+	if err != nil {
+		return false
+	}
+	return !lastModified(resource.ContentRevision).Truncate(time.Second).After(since)
+}
+
+// writeNotModified answers a conditional read with 304 and the validators of the representation the client already holds
+func writeNotModified(w http.ResponseWriter, resource *mapper.Resource, representation string) {
+	setRepresentationHeaders(w, resource, representation)
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// parseETags reads the content hashes and revisions from an If-Match or If-None-Match header value, whatever the representation and the content coding they were served in.
+// Weak tags are only honoured when weak is true, and tags not produced by nativerw never match.
+func parseETags(header string, weak bool) *db.RevisionCondition {
+	condition := &db.RevisionCondition{Revisions: []int64{}}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			condition.Any = true
+			continue
+		}
+
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		tag, _, _ = strings.Cut(strings.Trim(withoutContentCoding(tag), `"`), "+")
+		if isContentHash(tag) {
+			condition.Hashes = append(condition.Hashes, tag)
+			continue
+		}
+		revision, err := strconv.ParseInt(tag, 10, 64)
+		if err != nil {
+			continue
+		}
+		condition.Revisions = append(condition.Revisions, revision)
+	}
+	return condition
+}
+
+// preconditionFromRequest returns the precondition expressed by the If-Match and If-None-Match headers, or nil if there is none
+func preconditionFromRequest(r *http.Request) *db.Precondition {
+	ifMatch := r.Header.Get(IfMatchHeader)
+	ifNoneMatch := r.Header.Get(IfNoneMatchHeader)
+	if ifMatch == "" && ifNoneMatch == "" {
+		return nil
+	}
+
+	precondition := &db.Precondition{}
+	if ifMatch != "" {
+		precondition.IfMatch = parseETags(ifMatch, false)
+	}
+	if ifNoneMatch != "" {
+		precondition.IfNoneMatch = parseETags(ifNoneMatch, true)
+	}
+	return precondition
+}
+
+// writeResource stores the resource with its content hash, atomically checking the precondition against the latest revision when one is given
+func writeResource(connection db.Connection, collection string, resource *mapper.Resource, precondition *db.Precondition) error {
+	if err := HashResource(resource); err != nil {
+		return err
+	}
+	if precondition == nil {
+		return connection.Write(collection, resource)
+	}
+	return connection.WriteConditionally(collection, resource, *precondition)
+}
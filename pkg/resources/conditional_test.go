@@ -0,0 +1,104 @@
+package resources
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+// titleHash is the content hash of {"title":"Title"}
+const titleHash = "6ed8ab4970c4ccfbc5eb3d56d30dd3b5090282f08cf7268ad2c48bd0"
+
+func TestFormatETag(t *testing.T) {
+	assert.Equal(t, `"`+titleHash+`"`, formatETag(titleHash, ""))
+	assert.Equal(t, `"`+titleHash+`+cbor"`, formatETag(titleHash, "cbor"))
+}
+
+func TestEntityTag(t *testing.T) {
+	assert.Equal(t, titleHash, entityTag(&mapper.Resource{ContentHash: titleHash, ContentRevision: 1436773875771421417}))
+	assert.Equal(t, "1436773875771421417", entityTag(&mapper.Resource{ContentRevision: 1436773875771421417, Deleted: true}))
+}
+
+func TestRepresentationOf(t *testing.T) {
+	assert.Empty(t, representationOf("application/json", "application/json"))
+	assert.Empty(t, representationOf("application/vnd.ft-upp-article+json; charset=utf-8", "application/vnd.ft-upp-article+json; charset=utf-8"))
+	assert.Equal(t, "yaml", representationOf("application/json", "application/yaml"))
+	assert.Equal(t, "x-msgpack", representationOf("application/json", "application/x-msgpack"))
+}
+
+func TestParseETags(t *testing.T) {
+	condition := parseETags(`"1", W/"2", "not-a-revision", "3"`, false)
+	assert.Equal(t, &db.RevisionCondition{Revisions: []int64{1, 3}}, condition)
+
+	condition = parseETags(`"1", W/"2"`, true)
+	assert.Equal(t, &db.RevisionCondition{Revisions: []int64{1, 2}}, condition)
+
+	condition = parseETags(`"1+yaml", W/"2+cbor"`, true)
+	assert.Equal(t, &db.RevisionCondition{Revisions: []int64{1, 2}}, condition)
+
+	condition = parseETags(`"1+gzip", "2+yaml+deflate"`, false)
+	assert.Equal(t, &db.RevisionCondition{Revisions: []int64{1, 2}}, condition)
+
+	condition = parseETags(`"`+titleHash+`+yaml+gzip", "1"`, false)
+	assert.Equal(t, &db.RevisionCondition{Revisions: []int64{1}, Hashes: []string{titleHash}}, condition)
+
+	condition = parseETags(`*`, false)
+	assert.Equal(t, &db.RevisionCondition{Any: true, Revisions: []int64{}}, condition)
+}
+
+func TestPreconditionFromRequest(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", http.NoBody)
+	assert.Nil(t, preconditionFromRequest(req))
+
+	req.Header.Set(IfMatchHeader, `"1"`)
+	req.Header.Set(IfNoneMatchHeader, `*`)
+	expected := &db.Precondition{
+		IfMatch:     &db.RevisionCondition{Revisions: []int64{1}},
+		IfNoneMatch: &db.RevisionCondition{Any: true, Revisions: []int64{}},
+	}
+	assert.Equal(t, expected, preconditionFromRequest(req))
+}
+
+func TestNotModified(t *testing.T) {
+	resource := &mapper.Resource{ContentHash: titleHash, ContentRevision: 1436773875771421417} // 2015-07-13T07:51:15Z
+	unhashed := &mapper.Resource{ContentRevision: 1436773875771421417}
+
+	var tests = []struct {
+		name           string
+		headers        map[string]string
+		resource       *mapper.Resource
+		representation string
+		expected       bool
+	}{
+		{"matching etag", map[string]string{IfNoneMatchHeader: `"` + titleHash + `"`}, resource, "", true},
+		{"matching weak etag", map[string]string{IfNoneMatchHeader: `W/"` + titleHash + `"`}, resource, "", true},
+		{"any etag", map[string]string{IfNoneMatchHeader: `*`}, resource, "", true},
+		{"other etag", map[string]string{IfNoneMatchHeader: `"1"`}, resource, "", false},
+		{"revision etag of a hashed revision", map[string]string{IfNoneMatchHeader: `"1436773875771421417"`}, resource, "", false},
+		{"revision etag of a revision without hash", map[string]string{IfNoneMatchHeader: `"1436773875771421417"`}, unhashed, "", true},
+		{"matching representation", map[string]string{IfNoneMatchHeader: `"1", "` + titleHash + `+yaml"`}, resource, "yaml", true},
+		{"other representation", map[string]string{IfNoneMatchHeader: `"` + titleHash + `+yaml"`}, resource, "cbor", false},
+		{"compressed representation", map[string]string{IfNoneMatchHeader: `"` + titleHash + `+gzip"`}, resource, "", true},
+		{"compressed rendering", map[string]string{IfNoneMatchHeader: `"` + titleHash + `+yaml+deflate"`}, resource, "yaml", true},
+		{"stored representation", map[string]string{IfNoneMatchHeader: `"` + titleHash + `"`}, resource, "yaml", false},
+		{"modified since", map[string]string{IfModifiedSinceHeader: "Mon, 13 Jul 2015 07:51:14 GMT"}, resource, "", false},
+		{"not modified since", map[string]string{IfModifiedSinceHeader: "Mon, 13 Jul 2015 07:51:15 GMT"}, resource, "", true},
+		{"invalid date", map[string]string{IfModifiedSinceHeader: "yesterday"}, resource, "", false},
+		{"etag takes precedence over date", map[string]string{IfNoneMatchHeader: `"1"`, IfModifiedSinceHeader: "Mon, 13 Jul 2015 07:51:15 GMT"}, resource, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+			for name, value := range test.headers {
+				req.Header.Set(name, value)
+			}
+			assert.True(t, isConditionalRead(req))
+			assert.Equal(t, test.expected, notModified(req, test.resource, test.representation))
+		})
+	}
+}
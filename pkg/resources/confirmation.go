@@ -0,0 +1,136 @@
+package resources
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// PurgeConfirmationHeader carries the token confirming a purge
+const PurgeConfirmationHeader = "X-Purge-Confirmation"
+
+var errInvalidConfirmation = errors.New("invalid or expired purge confirmation token")
+
+// PurgeConfirmation issues and verifies the short-lived tokens confirming purges.
+// A token is signed with a secret shared by all the instances of the service, and is only valid for the request it was issued for,
+// from the same requester, while the document still has the revisions it had then. A purge changes them, so a token confirms a single purge.
+type PurgeConfirmation struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewPurgeConfirmation creates tokens valid for the given duration
+func NewPurgeConfirmation(secret []byte, ttl time.Duration) *PurgeConfirmation {
+	return &PurgeConfirmation{secret: secret, ttl: ttl, now: time.Now}
+}
+
+func (c *PurgeConfirmation) issue(r *http.Request, revisions []int64) (string, time.Time) {
+	expires := c.now().Add(c.ttl).Truncate(time.Second)
+	expiresStr := strconv.FormatInt(expires.Unix(), 10)
+	return expiresStr + "." + c.sign(r, revisions, expiresStr), expires
+}
+
+func (c *PurgeConfirmation) verify(r *http.Request, revisions []int64, token string) error {
+	expiresStr, signature, found := strings.Cut(token, ".")
+	if !found {
+		return errInvalidConfirmation
+	}
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil {
+		return errInvalidConfirmation
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(c.sign(r, revisions, expiresStr))) || !c.now().Before(time.Unix(expires, 0)) {
+		return errInvalidConfirmation
+	}
+	return nil
+}
+
+func (c *PurgeConfirmation) sign(r *http.Request, revisions []int64, expires string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	fmt.Fprintf(mac, "%s %s\n%s\n%v\n%s", r.Method, r.URL.Path, r.Header.Get(RequestedByHeader), revisions, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ConfirmPurge requires purges to be confirmed in two steps. A first call answers with a confirmation token,
+// and the purge is only executed when the same request is repeated with the token in the X-Purge-Confirmation header.
+// Dry runs are never held back, and nothing is required when confirmation is nil.
+func (f *Filters) ConfirmPurge(connection db.Connection, confirmation *PurgeConfirmation) *Filters {
+	if confirmation == nil {
+		return f
+	}
+
+	next := f.next
+	f.next = func(w http.ResponseWriter, r *http.Request) {
+		if isDryRun(r) {
+			next(w, r)
+			return
+		}
+
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+		vars := mux.Vars(r)
+		revisions, err := connection.ReadRevisions(vars["collection"], vars["resource"])
+		if err != nil {
+			defer r.Body.Close()
+
+			msg := "Reading from mongoDB failed."
+			logger.WithTransactionID(tid).WithUUID(vars["resource"]).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		token := r.Header.Get(PurgeConfirmationHeader)
+		if token == "" {
+			defer r.Body.Close()
+
+			token, expires := confirmation.issue(r, revisions)
+			logger.WithTransactionID(tid).Info("Issued a purge confirmation token")
+
+			respBody, _ := json.Marshal(struct {
+				Message string `json:"message"`
+				Token   string `json:"token"`
+				Expires string `json:"expires"`
+			}{
+				Message: fmt.Sprintf("Repeat the request with the %s header to confirm the purge", PurgeConfirmationHeader),
+				Token:   token,
+				Expires: expires.UTC().Format(time.RFC3339),
+			})
+
+			w.Header().Set(PurgeConfirmationHeader, token)
+			w.Header().Add("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, string(respBody))
+			return
+		}
+
+		if err := confirmation.verify(r, revisions, token); err != nil {
+			defer r.Body.Close()
+
+			logger.WithTransactionID(tid).WithError(err).Warn("Purge confirmation failed")
+			writeMessage(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+	return f
+}
+
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	return dryRun
+}
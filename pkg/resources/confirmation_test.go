@@ -0,0 +1,171 @@
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func confirmedPurgeRouter(connection *MockConnection, confirmation *PurgeConfirmation, purged *bool) *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/purge/{resource}",
+		Filter(func(w http.ResponseWriter, r *http.Request) { *purged = true }).
+			ConfirmPurge(connection, confirmation).
+			Build()).
+		Methods("DELETE")
+	return router
+}
+
+func documentWithRevisions(revisions ...int64) *MockConnection {
+	connection := new(MockConnection)
+	connection.On("ReadRevisions", "universal-content", "a-real-uuid").Return(revisions, nil)
+	return connection
+}
+
+func TestConfirmPurge(t *testing.T) {
+	confirmation := NewPurgeConfirmation([]byte("secret"), time.Minute)
+	purged := false
+	router := confirmedPurgeRouter(documentWithRevisions(1, 2), confirmation, &purged)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.False(t, purged)
+
+	var body struct {
+		Token   string `json:"token"`
+		Expires string `json:"expires"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, body.Token, w.Header().Get(PurgeConfirmationHeader))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	req.Header.Add(PurgeConfirmationHeader, body.Token)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, purged)
+}
+
+func TestConfirmPurgeRejectsTokenForAnotherRequest(t *testing.T) {
+	confirmation := NewPurgeConfirmation([]byte("secret"), time.Minute)
+	purged := false
+	router := confirmedPurgeRouter(documentWithRevisions(1, 2), confirmation, &purged)
+
+	issuedFor, _ := http.NewRequest("DELETE", "/universal-content/purge/another-uuid", http.NoBody)
+	token, _ := confirmation.issue(issuedFor, []int64{1, 2})
+
+	for _, token := range []string{token, "not-a-token", "1.abc", ""} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+		req.Header.Add(PurgeConfirmationHeader, token)
+		router.ServeHTTP(w, req)
+
+		assert.NotEqual(t, http.StatusOK, w.Code, token)
+	}
+	assert.False(t, purged)
+}
+
+func TestConfirmPurgeRejectsTokenOfAnotherRequester(t *testing.T) {
+	confirmation := NewPurgeConfirmation([]byte("secret"), time.Minute)
+	purged := false
+	router := confirmedPurgeRouter(documentWithRevisions(1, 2), confirmation, &purged)
+
+	issuedFor, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	issuedFor.Header.Set(RequestedByHeader, "editor")
+	token, _ := confirmation.issue(issuedFor, []int64{1, 2})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	req.Header.Set(RequestedByHeader, "someone-else")
+	req.Header.Add(PurgeConfirmationHeader, token)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, purged)
+}
+
+func TestConfirmPurgeRejectsReplayedToken(t *testing.T) {
+	confirmation := NewPurgeConfirmation([]byte("secret"), time.Minute)
+
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	token, _ := confirmation.issue(req, []int64{1, 2})
+
+	for _, revisions := range [][]int64{{2}, {}, {1, 2, 3}} {
+		purged := false
+		router := confirmedPurgeRouter(documentWithRevisions(revisions...), confirmation, &purged)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+		req.Header.Add(PurgeConfirmationHeader, token)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code, revisions)
+		assert.False(t, purged, revisions)
+	}
+}
+
+func TestConfirmPurgeFailsToReadRevisions(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisions", "universal-content", "a-real-uuid").Return([]int64(nil), errors.New("no mongo"))
+	purged := false
+	router := confirmedPurgeRouter(connection, NewPurgeConfirmation([]byte("secret"), time.Minute), &purged)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.False(t, purged)
+}
+
+func TestConfirmPurgeRejectsExpiredToken(t *testing.T) {
+	confirmation := NewPurgeConfirmation([]byte("secret"), time.Minute)
+	purged := false
+	router := confirmedPurgeRouter(documentWithRevisions(1, 2), confirmation, &purged)
+
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	token, _ := confirmation.issue(req, []int64{1, 2})
+	confirmation.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	w := httptest.NewRecorder()
+	req.Header.Add(PurgeConfirmationHeader, token)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.False(t, purged)
+}
+
+func TestConfirmPurgeLetsDryRunsThrough(t *testing.T) {
+	purged := false
+	router := confirmedPurgeRouter(new(MockConnection), NewPurgeConfirmation([]byte("secret"), time.Minute), &purged)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid?dryRun=true", http.NoBody)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, purged)
+}
+
+func TestConfirmPurgeDisabled(t *testing.T) {
+	purged := false
+	router := confirmedPurgeRouter(new(MockConnection), nil, &purged)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, purged)
+}
@@ -0,0 +1,23 @@
+package resources
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+// ReadContentTypes lists the media type patterns of the content types which can be written and read, from the most to the least specific
+func ReadContentTypes(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(mapper.Patterns())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	if _, err := w.Write(data); err != nil {
+		logger.WithError(err).Error("could not write the content types")
+	}
+}
@@ -0,0 +1,19 @@
+package resources
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadContentTypes(t *testing.T) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/__content-types", http.NoBody)
+
+	ReadContentTypes(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `["application/json","application/xml","text/xml","application/*+json","application/*+xml","*/*"]`, w.Body.String())
+}
@@ -0,0 +1,96 @@
+package resources
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// DeleteContent marks a native record as deleted by writing a tombstone as its new revision.
+// Its earlier revisions stay readable.
+func DeleteContent(connection db.Connection, ts TimestampCreator) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		collectionID := mux.Vars(r)["collection"]
+		resourceID := mux.Vars(r)["resource"]
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+
+		contentType := extractAttrFromHeader(r, "Content-Type", "application/json", tid, resourceID)
+		originSystemIDHeader := extractAttrFromHeader(r, "Origin-System-Id", "", tid, resourceID)
+		schemaVersion := r.Header.Get(SchemaVersionHeader)
+
+		latest, found, err := connection.ReadMetadata(collectionID, resourceID)
+		if err != nil {
+			msg := "Reading from mongoDB failed."
+			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		if !found {
+			msg := fmt.Sprintf("Could not delete resource, not found, collection=%v, id=%v", collectionID, resourceID)
+			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).Info(msg)
+			writeMessage(w, msg, http.StatusNotFound)
+			return
+		}
+
+		if latest.Deleted {
+			logger.WithMonitoringEvent("SaveToNative", tid, contentType).
+				WithUUID(resourceID).
+				WithField("collection", collectionID).
+				WithField("content-revision", latest.ContentRevision).
+				Info("Content is already deleted. Skipping delete")
+
+			w.Header().Set(ContentRevisionHeader, strconv.FormatInt(latest.ContentRevision, 10))
+			setRevisionHeaders(w, latest)
+			return
+		}
+
+		contentRevision := ts.CreateTimestamp()
+		tombstone := mapper.Tombstone(resourceID, contentType, originSystemIDHeader, schemaVersion, contentRevision)
+
+		err = writeResource(connection, collectionID, tombstone, preconditionFromRequest(r))
+		if errors.Is(err, db.ErrPreconditionFailed) {
+			msg := "Precondition failed"
+			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Warn(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusPreconditionFailed)
+			return
+		}
+		if err != nil {
+			msg := "Writing to mongoDB failed"
+			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set(ContentRevisionHeader, strconv.FormatInt(contentRevision, 10))
+		setRevisionHeaders(w, tombstone)
+
+		logger.WithMonitoringEvent("SaveToNative", tid, contentType).
+			WithUUID(resourceID).
+			WithField("collection", collectionID).
+			WithField("origin-system-id", originSystemIDHeader).
+			WithField("schema-version", schemaVersion).
+			WithField("content-revision", contentRevision).
+			Info("Successfully deleted")
+	}
+}
+
+// writeGone answers a read of a deleted document, with the revision of the deletion in the headers
+func writeGone(w http.ResponseWriter, tombstone *mapper.Resource, tid string) {
+	msg := fmt.Sprintf("Resource has been deleted, id=%v, revision=%v", tombstone.UUID, tombstone.ContentRevision)
+	logger.WithTransactionID(tid).WithUUID(tombstone.UUID).Info(msg)
+
+	w.Header().Add(ContentRevisionHeader, strconv.FormatInt(tombstone.ContentRevision, 10))
+	setRevisionHeaders(w, tombstone)
+	writeMessage(w, msg, http.StatusGone)
+}
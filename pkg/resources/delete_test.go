@@ -0,0 +1,156 @@
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func TestDeleteContentWritesTombstone(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 1}, true, nil)
+	connection.On("Write",
+		"universal-content",
+		&mapper.Resource{
+			UUID:            "a-real-uuid",
+			ContentType:     "application/json",
+			OriginSystemID:  "methode-web-pub",
+			SchemaVersion:   "3",
+			ContentRevision: 1436773875771421417,
+			Deleted:         true}).
+		Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", DeleteContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Add("Origin-System-Id", "methode-web-pub")
+	req.Header.Add(SchemaVersionHeader, "3")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1436773875771421417", w.Header().Get(ContentRevisionHeader))
+}
+
+func TestDeleteContentNotFound(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return((*mapper.Resource)(nil), false, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", DeleteContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDeleteContentAlreadyDeleted(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 2, Deleted: true}, true, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", DeleteContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(ContentRevisionHeader))
+}
+
+func TestDeleteContentIfMatchFailed(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 2}, true, nil)
+	connection.On("WriteConditionally",
+		"universal-content",
+		&mapper.Resource{
+			UUID:            "a-real-uuid",
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417,
+			Deleted:         true},
+		db.IfLatest(1)).
+		Return(db.ErrPreconditionFailed)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", DeleteContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Add("If-Match", `"1"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestDeleteContentReadFails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return((*mapper.Resource)(nil), false, errors.New("i failed"))
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", DeleteContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestReadDeletedContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 2, Deleted: true}, true, nil)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(2)).
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 2, Deleted: true}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+	router.HandleFunc("/{collection}/{resource}/{revision}", ReadSingleRevision(connection)).Methods("GET")
+
+	for _, path := range []string{"/universal-content/a-real-uuid", "/universal-content/a-real-uuid/2"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, http.NoBody)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusGone, w.Code, path)
+		assert.Equal(t, "2", w.Header().Get(ContentRevisionHeader))
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+
+		var body map[string]string
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Contains(t, body["message"], "deleted")
+	}
+	connection.AssertExpectations(t)
+}
@@ -0,0 +1,184 @@
+package resources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/jsonpatch"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+	"github.com/Financial-Times/nativerw/pkg/textdiff"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// DiffRevisions describes the changes between two revisions of a document as a JSON Patch, or as a unified diff with ?format=unified.
+// The to revision defaults to the latest one, and the from revision to the one before it, skipping the deletions.
+// A deletion requested explicitly is answered with 410 Gone, as it has no content to compare.
+func DiffRevisions(connection db.Connection) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+		vars := mux.Vars(r)
+		uuid := vars["resource"]
+		collection := vars["collection"]
+		query := r.URL.Query()
+		var to int64
+
+		from, err := parseOptionalRevision(query.Get("from"))
+		if err == nil {
+			to, err = parseOptionalRevision(query.Get("to"))
+		}
+		if err != nil {
+			msg := "Invalid content-revision"
+			logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		if from == 0 || to == 0 {
+			revisions, err := readLiveRevisions(r.Context(), connection, collection, uuid)
+			if err != nil {
+				msg := "Reading from mongoDB failed."
+				logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+				http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+				return
+			}
+
+			var found bool
+			from, to, found = defaultDiffRevisions(revisions, from, to)
+			if !found {
+				msg := fmt.Sprintf("Revisions to compare not found, collection=%v, id=%v", collection, uuid)
+				logger.WithTransactionID(tid).WithUUID(uuid).Info(msg)
+				writeMessage(w, msg, http.StatusNotFound)
+				return
+			}
+		}
+
+		fromResource, ok := readRevisionToDiff(w, connection, collection, uuid, from, tid)
+		if !ok {
+			return
+		}
+		toResource, ok := readRevisionToDiff(w, connection, collection, uuid, to, tid)
+		if !ok {
+			return
+		}
+
+		if query.Get("format") == "unified" {
+			fromName := fmt.Sprintf("a/%s/%d", uuid, from)
+			toName := fmt.Sprintf("b/%s/%d", uuid, to)
+			w.Header().Add("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, textdiff.Unified(fromName, toName, contentLines(fromResource.Content), contentLines(toResource.Content)))
+			return
+		}
+
+		if isRawContent(fromResource.Content) || isRawContent(toResource.Content) {
+			msg := fmt.Sprintf("Only JSON revisions can be compared as a JSON Patch, use format=unified instead, collection=%v, id=%v", collection, uuid)
+			logger.WithTransactionID(tid).WithUUID(uuid).Info(msg)
+			writeMessage(w, msg, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		respBody, err := json.Marshal(jsonpatch.Diff(fromResource.Content, toResource.Content))
+		if err != nil {
+			msg := "Unable to serialize the diff."
+			logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Add("Content-Type", jsonPatchContentType)
+		fmt.Fprint(w, string(respBody))
+	}
+}
+
+// parseOptionalRevision parses a revision query parameter, returning zero when it is not set
+func parseOptionalRevision(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// readLiveRevisions lists the revisions of a document which are not deletions, without reading their content
+func readLiveRevisions(ctx context.Context, connection db.Connection, collection, uuid string) ([]int64, error) {
+	resources, err := connection.ReadRevisionsMetadata(ctx, collection, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]int64, 0, len(resources))
+	for _, resource := range resources {
+		if !resource.Deleted {
+			revisions = append(revisions, resource.ContentRevision)
+		}
+	}
+	return revisions, nil
+}
+
+// defaultDiffRevisions fills in the latest revision for to, and the revision preceding to for from
+func defaultDiffRevisions(revisions []int64, from, to int64) (int64, int64, bool) {
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i] < revisions[j] })
+	if len(revisions) == 0 {
+		return 0, 0, false
+	}
+
+	if to == 0 {
+		to = revisions[len(revisions)-1]
+	}
+	if from == 0 {
+		i := sort.Search(len(revisions), func(i int) bool { return revisions[i] >= to })
+		if i == 0 {
+			return 0, 0, false
+		}
+		from = revisions[i-1]
+	}
+	return from, to, true
+}
+
+func readRevisionToDiff(w http.ResponseWriter, connection db.Connection, collection, uuid string, revision int64, tid string) (*mapper.Resource, bool) {
+	resource, err := connection.ReadSingleRevision(collection, uuid, revision)
+	if err != nil {
+		msg := "Reading from mongoDB failed."
+		logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if resource == nil {
+		msg := fmt.Sprintf("Resource not found, collection=%v, id=%v, revision=%v", collection, uuid, revision)
+		logger.WithTransactionID(tid).WithUUID(uuid).Info(msg)
+		writeMessage(w, msg, http.StatusNotFound)
+		return nil, false
+	}
+
+	if resource.Deleted {
+		writeGone(w, resource, tid)
+		return nil, false
+	}
+
+	return resource, true
+}
+
+// isRawContent checks whether a content is stored as raw bytes, like XML and the other content types which are not JSON
+func isRawContent(content interface{}) bool {
+	_, raw := content.([]byte)
+	return raw
+}
+
+// contentLines returns the lines of a revision to compare: the pretty-printed JSON content, or the bytes of a raw content read as UTF-8 text
+func contentLines(content interface{}) []string {
+	if data, raw := content.([]byte); raw {
+		return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	}
+	data, _ := json.MarshalIndent(content, "", "  ")
+	return strings.Split(string(data), "\n")
+}
@@ -0,0 +1,251 @@
+package resources
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func mockRevision(connection *MockConnection, revision int64, content map[string]interface{}) {
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", revision).
+		Return(
+			&mapper.Resource{
+				ContentType:     "application/json",
+				Content:         content,
+				ContentRevision: revision},
+			nil)
+}
+
+func revisionsMetadata(revisions ...int64) []*mapper.Resource {
+	resources := make([]*mapper.Resource, len(revisions))
+	for i, revision := range revisions {
+		resources[i] = &mapper.Resource{UUID: "a-real-uuid", ContentRevision: revision}
+	}
+	return resources
+}
+
+func TestDiffRevisionsDefaultsToLatestChange(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").Return(revisionsMetadata(3, 1, 2), nil)
+	mockRevision(connection, 2, map[string]interface{}{"title": "Title", "tags": []interface{}{"a"}})
+	mockRevision(connection, 3, map[string]interface{}{"title": "New title", "tags": []interface{}{"a", "b"}})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json-patch+json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[{"op":"add","path":"/tags/-","value":"b"},{"op":"replace","path":"/title","value":"New title"}]`, w.Body.String())
+}
+
+func TestDiffRevisionsBetweenGivenRevisions(t *testing.T) {
+	connection := new(MockConnection)
+	mockRevision(connection, 1, map[string]interface{}{"title": "Title", "body": "Body"})
+	mockRevision(connection, 3, map[string]interface{}{"title": "Title"})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff?from=1&to=3", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"op":"remove","path":"/body"}]`, w.Body.String())
+}
+
+func TestDiffRevisionsUnifiedFormat(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").Return(revisionsMetadata(1, 2, 3), nil)
+	mockRevision(connection, 1, map[string]interface{}{"title": "Title", "body": "Body"})
+	mockRevision(connection, 3, map[string]interface{}{"title": "New title", "body": "Body"})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff?from=1&format=unified", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+	expected := `--- a/a-real-uuid/1
++++ b/a-real-uuid/3
+@@ -1,4 +1,4 @@
+ {
+   "body": "Body",
+-  "title": "Title"
++  "title": "New title"
+ }
+`
+	assert.Equal(t, expected, w.Body.String())
+}
+
+func TestDiffRevisionsWithSingleRevision(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").Return(revisionsMetadata(1), nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDiffRevisionsSkipsDeletions(t *testing.T) {
+	connection := new(MockConnection)
+	revisions := revisionsMetadata(1, 2, 3, 4)
+	revisions[1].Deleted = true
+	revisions[3].Deleted = true
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").Return(revisions, nil)
+	mockRevision(connection, 1, map[string]interface{}{"title": "Title"})
+	mockRevision(connection, 3, map[string]interface{}{"title": "New title"})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"op":"replace","path":"/title","value":"New title"}]`, w.Body.String())
+}
+
+func TestDiffRevisionsWithOnlyDeletionsBefore(t *testing.T) {
+	connection := new(MockConnection)
+	revisions := revisionsMetadata(1, 2)
+	revisions[0].Deleted = true
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").Return(revisions, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "ReadSingleRevision", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDiffRevisionsDeletionRequested(t *testing.T) {
+	connection := new(MockConnection)
+	mockRevision(connection, 1, map[string]interface{}{"title": "Title"})
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(2)).
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentRevision: 2, Deleted: true}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff?from=1&to=2", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, "2", w.Header().Get(ContentRevisionHeader))
+}
+
+func TestDiffRevisionsMissingRevision(t *testing.T) {
+	connection := new(MockConnection)
+	mockRevision(connection, 1, map[string]interface{}{"title": "Title"})
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(2)).Return((*mapper.Resource)(nil), nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff?from=1&to=2", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestDiffRevisionsInvalidRevision(t *testing.T) {
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff?from=abc", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.True(t, strings.HasPrefix(w.Body.String(), "Invalid content-revision"))
+}
+
+func mockRawRevision(connection *MockConnection, revision int64, content string) {
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", revision).
+		Return(
+			&mapper.Resource{
+				ContentType:     "application/xml",
+				Content:         []byte(content),
+				ContentRevision: revision},
+			nil)
+}
+
+func TestDiffRawRevisionsUnifiedFormat(t *testing.T) {
+	connection := new(MockConnection)
+	mockRawRevision(connection, 1, "<doc>\n  <title>Title</title>\n  <body>Body</body>\n</doc>\n")
+	mockRawRevision(connection, 2, "<doc>\n  <title>New title</title>\n  <body>Body</body>\n</doc>\n")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff?from=1&to=2&format=unified", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	expected := `--- a/a-real-uuid/1
++++ b/a-real-uuid/2
+@@ -1,4 +1,4 @@
+ <doc>
+-  <title>Title</title>
++  <title>New title</title>
+   <body>Body</body>
+ </doc>
+`
+	assert.Equal(t, expected, w.Body.String())
+}
+
+func TestDiffRawRevisionsAsJSONPatch(t *testing.T) {
+	connection := new(MockConnection)
+	mockRevision(connection, 1, map[string]interface{}{"title": "Title"})
+	mockRawRevision(connection, 2, "<doc><title>Title</title></doc>")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/diff", DiffRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/diff?from=1&to=2", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
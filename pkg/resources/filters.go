@@ -13,8 +13,10 @@ import (
 )
 
 const (
-	SchemaVersionHeader   = "X-Schema-Version"
-	ContentRevisionHeader = "X-Content-Revision"
+	SchemaVersionHeader       = "X-Schema-Version"
+	ContentRevisionHeader     = "X-Content-Revision"
+	NativeHashHeader          = "X-Native-Hash"
+	NativeHashAlgorithmHeader = "X-Native-Hash-Algorithm"
 )
 
 var uuidRegexp = regexp.MustCompile("^[a-f0-9]{8}-[a-f0-9]{4}-[1-5][a-f0-9]{3}-[a-f0-9]{4}-[a-f0-9]{12}$")
@@ -117,6 +119,27 @@ func (f *Filters) ValidateAccessForCollection(connection db.Connection) *Filters
 	return f
 }
 
+// LimitBodySize rejects the requests whose body is larger than limit bytes with 413 Request Entity Too Large.
+// A body without a Content-Length is cut at the limit, and the handler reading it answers 413 as well.
+func (f *Filters) LimitBodySize(limit int64) *Filters {
+	next := f.next
+	f.next = func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			defer r.Body.Close()
+
+			tid := transactionidutils.GetTransactionIDFromRequest(r)
+			msg := fmt.Sprintf("request body is larger than the limit of %d bytes", limit)
+			logger.WithTransactionID(tid).WithField("content-length", r.ContentLength).Error(msg)
+			http.Error(w, msg, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+	return f
+}
+
 // Filters wraps the next http handler
 type Filters struct {
 	next func(w http.ResponseWriter, r *http.Request)
@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/Financial-Times/go-logger"
 )
@@ -94,3 +96,42 @@ func TestValidateAccessForCollection(t *testing.T) {
 		}
 	}
 }
+
+func TestLimitBodySize(t *testing.T) {
+	forwarded := false
+	next := func(w http.ResponseWriter, r *http.Request) {
+		forwarded = true
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(next).LimitBodySize(8).Build()).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{"title":"Title"}`))
+
+	router.ServeHTTP(w, req)
+	assert.False(t, forwarded)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
+
+	router.ServeHTTP(w, req)
+	assert.True(t, forwarded)
+}
+
+func TestLimitBodySizeWithoutContentLength(t *testing.T) {
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(WriteContent(connection, &fixedTimestampCreator{}, nil)).LimitBodySize(8).Build()).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{"title":"Title"}`))
+	req.ContentLength = -1
+	req.Header.Add("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -12,9 +13,18 @@ import (
 
 	"github.com/Financial-Times/go-logger"
 	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/jcs"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
 	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
 )
 
+const (
+	// LegacyHashAlgorithm hashes the Go JSON encoding of the content with SHA-224, and is used when no algorithm is asked for
+	LegacyHashAlgorithm = "sha224"
+	// CanonicalHashAlgorithm hashes the RFC 8785 canonical JSON serialisation of the content with SHA-256
+	CanonicalHashAlgorithm = "sha256-jcs"
+)
+
 // Hash hashes the given payload in SHA224 + Hex
 func Hash(payload string) string {
 	hash := sha256.New224()
@@ -26,19 +36,29 @@ func Hash(payload string) string {
 	return hex.EncodeToString(hash.Sum(nil))
 }
 
-// CheckNativeHash will check for the X-Native-Hash header and compare it to the current saved copy of the same resource
+// CheckNativeHash will check for the X-Native-Hash header and compare it to the current saved copy of the same resource,
+// using the algorithm given in X-Native-Hash-Algorithm
 func (f *Filters) CheckNativeHash(connection db.Connection) *Filters {
 	next := f.next
 
 	f.next = func(w http.ResponseWriter, r *http.Request) {
-		nativeHash := r.Header.Get("X-Native-Hash")
+		nativeHash := r.Header.Get(NativeHashHeader)
 
 		if strings.TrimSpace(nativeHash) != "" {
 			defer r.Body.Close()
 
 			tid := transactionidutils.GetTransactionIDFromRequest(r)
 			vars := mux.Vars(r)
-			matches, err := checkNativeHash(connection, nativeHash, vars["collection"], vars["resource"])
+
+			algorithm, err := hashAlgorithmFromRequest(r)
+			if err != nil {
+				msg := "Invalid native hash algorithm"
+				logger.WithTransactionID(tid).WithError(err).Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+				return
+			}
+
+			matches, err := checkNativeHash(connection, nativeHash, algorithm, vars["collection"], vars["resource"])
 
 			if err != nil {
 				msg := "Unexpected error occurred while checking the native hash"
@@ -63,23 +83,163 @@ func (f *Filters) CheckNativeHash(connection db.Connection) *Filters {
 	return f
 }
 
-func checkNativeHash(mongo db.Connection, hash string, collection string, id string) (bool, error) {
-	resource, found, err := mongo.Read(collection, id)
+func checkNativeHash(mongo db.Connection, hash string, algorithm string, collection string, id string) (bool, error) {
+	resource, found, err := readLatestHash(mongo, collection, id)
 	if err != nil {
 		return false, err
 	}
 
-	if !found {
+	if !found || resource.Deleted {
 		msg := fmt.Sprintf("Received a carousel publish but the original native content does not exist in the native store! collection=%s" + collection)
 		logger.WithTransactionID("").WithUUID(id).Warn(msg)
 		return false, nil // no native document for this id, so save it
 	}
 
-	data, err := json.Marshal(resource.Content)
+	return storedHash(resource, algorithm) == hash, nil
+}
+
+// hashAlgorithmFromRequest reads the algorithm of X-Native-Hash, which is the legacy one unless the client asks for another
+func hashAlgorithmFromRequest(r *http.Request) (string, error) {
+	algorithm := strings.ToLower(strings.TrimSpace(r.Header.Get(NativeHashAlgorithmHeader)))
+	switch algorithm {
+	case "":
+		return LegacyHashAlgorithm, nil
+	case LegacyHashAlgorithm, CanonicalHashAlgorithm:
+		return algorithm, nil
+	}
+	return "", fmt.Errorf("unsupported algorithm %q, expected %v or %v", algorithm, LegacyHashAlgorithm, CanonicalHashAlgorithm)
+}
+
+// storedHash returns the hash of a resource with the given algorithm
+func storedHash(resource *mapper.Resource, algorithm string) string {
+	if algorithm == CanonicalHashAlgorithm {
+		return resource.CanonicalHash
+	}
+	return resource.ContentHash
+}
+
+// readLatestHash reads the latest revision of a document without its content, but with its content hashes.
+// Revisions written before hashes were stored are read in full to compute them. The canonical hash is legitimately missing from content
+// with numbers out of the range of doubles, so only the legacy hash, which every content has, tells whether the hashes were stored.
+func readLatestHash(mongo db.Connection, collection string, id string) (*mapper.Resource, bool, error) {
+	resource, found, err := mongo.ReadMetadata(collection, id)
+	if err != nil || !found || resource.Deleted || resource.ContentHash != "" {
+		return resource, found, err
+	}
+
+	resource, found, err = mongo.Read(collection, id)
+	if err != nil || !found {
+		return resource, found, err
+	}
+	err = HashResource(resource)
+	return resource, found, err
+}
+
+// HashResource sets the missing content hashes and size of a resource, except for tombstones which have no content
+func HashResource(resource *mapper.Resource) error {
+	if resource.Deleted {
+		return nil
+	}
+
+	if resource.ContentHash == "" {
+		hash, err := ContentHash(resource.Content)
+		if err != nil {
+			return err
+		}
+		resource.ContentHash = hash
+	}
+	if resource.ContentSize == 0 {
+		data, err := serialisedContent(resource.Content)
+		if err != nil {
+			return err
+		}
+		resource.ContentSize = int64(len(data))
+	}
+	if resource.CanonicalHash == "" {
+		hash, err := CanonicalContentHash(resource.Content)
+		if err != nil {
+			return err
+		}
+		resource.CanonicalHash = hash
+	}
+	return nil
+}
+
+// setNativeHashHeader tells the client the hash to send in X-Native-Hash to avoid rewriting the same content
+func setNativeHashHeader(w http.ResponseWriter, resource *mapper.Resource, algorithm string) {
+	if err := HashResource(resource); err != nil {
+		logger.WithError(err).WithUUID(resource.UUID).Warn("Unable to hash the content")
+		return
+	}
+	hash := storedHash(resource, algorithm)
+	if hash == "" {
+		return
+	}
+	w.Header().Set(NativeHashHeader, hash)
+	w.Header().Set(NativeHashAlgorithmHeader, algorithm)
+}
+
+// ContentHash hashes the JSON serialisation of a content, or its raw bytes, with the legacy sha224 algorithm.
+// The numbers are serialised as doubles, as they were before they were kept exactly, so that the hash of a content does not change,
+// e.g. {"a":1.0} is hashed as {"a":1}.
+func ContentHash(content interface{}) (string, error) {
+	if data, ok := content.([]byte); ok {
+		return Hash(string(data)), nil
+	}
+	data, err := json.Marshal(doubleNumbers(content))
 	if err != nil {
-		return false, err
+		return "", err
 	}
+	return Hash(string(data)), nil
+}
 
-	existingHash := Hash(string(data))
-	return existingHash == hash, nil
+// doubleNumbers returns a copy of a content with its JSON numbers converted to doubles.
+// The numbers out of the range of doubles are kept as they are, as they could not be hashed before.
+func doubleNumbers(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(value))
+		for k, child := range value {
+			c[k] = doubleNumbers(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(value))
+		for i, child := range value {
+			c[i] = doubleNumbers(child)
+		}
+		return c
+	case json.Number:
+		if f, err := value.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+// CanonicalContentHash hashes the RFC 8785 canonical JSON serialisation of a content with SHA-256,
+// so the hash does not depend on the order of the keys or on how the numbers are written. Raw content is hashed as it is.
+// Content with numbers which RFC 8785 cannot represent, as they do not fit in a double, has no canonical hash.
+func CanonicalContentHash(content interface{}) (string, error) {
+	data, ok := content.([]byte)
+	if !ok {
+		var err error
+		data, err = jcs.Marshal(content)
+		if errors.Is(err, jcs.ErrUnsupportedNumber) {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// serialisedContent returns the raw bytes of a content stored as received, and the JSON serialisation of a decoded one
+func serialisedContent(content interface{}) ([]byte, error) {
+	if data, ok := content.([]byte); ok {
+		return data, nil
+	}
+	return json.Marshal(content)
 }
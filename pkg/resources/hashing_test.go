@@ -43,6 +43,8 @@ func TestHashCheckMatches(t *testing.T) {
 
 	connection := new(MockConnection)
 
+	// revisions written before hashes were stored are read in full
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json"}, true, nil)
 	connection.On("Read", "universal-content", "a-real-uuid").Return(expectedResource, true, nil)
 
 	router := mux.NewRouter()
@@ -61,6 +63,64 @@ func TestHashCheckMatches(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestHashCheckMatchesStoredHash(t *testing.T) {
+	passed := false
+	next := func(w http.ResponseWriter, r *http.Request) {
+		passed = true
+	}
+
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{
+		UUID:          "a-real-uuid",
+		ContentType:   "application/json",
+		ContentHash:   "5c7c9d98996c2d0692e1d6ded53faa1833cdadd26693bb8cb4084181",
+		CanonicalHash: "stored-canonical-hash",
+	}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(next).CheckNativeHash(connection).Build()).Methods("POST")
+
+	body := &mapper.MockBody{Body: strings.NewReader(`{}`)}
+	body.On("Close").Return(nil)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", body)
+	req.Header.Add(NativeHashHeader, "5c7c9d98996c2d0692e1d6ded53faa1833cdadd26693bb8cb4084181")
+
+	router.ServeHTTP(w, req)
+
+	mock.AssertExpectationsForObjects(t, connection, body)
+	connection.AssertNotCalled(t, "Read", mock.Anything, mock.Anything)
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHashCheckWithoutStoredCanonicalHash(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{
+		UUID:        "a-real-uuid",
+		ContentType: "application/json",
+		ContentHash: "stored-hash",
+	}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(func(w http.ResponseWriter, r *http.Request) {}).CheckNativeHash(connection).Build()).Methods("POST")
+
+	for algorithm, status := range map[string]int{LegacyHashAlgorithm: http.StatusOK, CanonicalHashAlgorithm: http.StatusConflict} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", http.NoBody)
+		req.Header.Add(NativeHashHeader, "stored-hash")
+		req.Header.Add(NativeHashAlgorithmHeader, algorithm)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, status, w.Code, algorithm)
+	}
+
+	// content with numbers out of the range of doubles has no canonical hash, and is not read again to compute it
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Read", mock.Anything, mock.Anything)
+}
+
 func TestHashCheckDoesntMatch(t *testing.T) {
 	passed := false
 	next := func(w http.ResponseWriter, r *http.Request) {
@@ -79,6 +139,8 @@ func TestHashCheckDoesntMatch(t *testing.T) {
 
 	connection := new(MockConnection)
 
+	// revisions written before hashes were stored are read in full
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json"}, true, nil)
 	connection.On("Read", "universal-content", "a-real-uuid").Return(expectedResource, true, nil)
 
 	router := mux.NewRouter()
@@ -105,7 +167,7 @@ func TestNoContentFound(t *testing.T) {
 	}
 
 	connection := new(MockConnection)
-	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{}, false, nil)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{}, false, nil)
 
 	router := mux.NewRouter()
 	router.HandleFunc("/{collection}/{resource}", Filter(next).CheckNativeHash(connection).Build()).Methods("POST")
@@ -131,7 +193,7 @@ func TestHashCheckContentReadFails(t *testing.T) {
 	}
 
 	connection := new(MockConnection)
-	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{}, false, errors.New("i failed"))
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{}, false, errors.New("i failed"))
 
 	router := mux.NewRouter()
 	router.HandleFunc("/{collection}/{resource}", Filter(next).CheckNativeHash(connection).Build()).Methods("POST")
@@ -163,6 +225,8 @@ func TestHashCheckJsonMarshalFails(t *testing.T) {
 	}
 
 	connection := new(MockConnection)
+	// revisions written before hashes were stored are read in full
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json"}, true, nil)
 	connection.On("Read", "universal-content", "a-real-uuid").Return(expectedResource, true, nil)
 
 	router := mux.NewRouter()
@@ -203,3 +267,125 @@ func TestNoHashCheckIfNoHeader(t *testing.T) {
 	assert.True(t, passed)
 	assert.Equal(t, http.StatusOK, w.Code)
 }
+
+// hashed sets the content hash expected to be stored with a resource
+func hashed(resource *mapper.Resource) *mapper.Resource {
+	if err := HashResource(resource); err != nil {
+		panic(err)
+	}
+	return resource
+}
+
+func TestHashCheckWithCanonicalAlgorithm(t *testing.T) {
+	passed := false
+	next := func(w http.ResponseWriter, r *http.Request) {
+		passed = true
+	}
+
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json"}, true, nil)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{
+		UUID:        "a-real-uuid",
+		Content:     map[string]interface{}{"b": 1.0, "a": "x"},
+		ContentType: "application/json",
+	}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(next).CheckNativeHash(connection).Build()).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Add(NativeHashHeader, "cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246")
+	req.Header.Add(NativeHashAlgorithmHeader, "SHA256-JCS")
+
+	router.ServeHTTP(w, req)
+
+	connection.AssertExpectations(t)
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestHashCheckWithInvalidAlgorithm(t *testing.T) {
+	passed := false
+	next := func(w http.ResponseWriter, r *http.Request) {
+		passed = true
+	}
+
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(next).CheckNativeHash(connection).Build()).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Add(NativeHashHeader, "cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246")
+	req.Header.Add(NativeHashAlgorithmHeader, "md5")
+
+	router.ServeHTTP(w, req)
+
+	connection.AssertNotCalled(t, "ReadMetadata", mock.Anything, mock.Anything)
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestContentHashOfNumbersIsUnchanged(t *testing.T) {
+	hash, err := ContentHash(map[string]interface{}{"a": json.Number("1.0")})
+	assert.NoError(t, err)
+	// the hash of {"a":1}, as computed when numbers were decoded as doubles
+	assert.Equal(t, "43a7ffef7f9908f0e2d12ad769a97a707dfd0d1112fcf9ad295de28c", hash)
+
+	resource := &mapper.Resource{Content: map[string]interface{}{"a": json.Number("1e3"), "b": json.Number("100.50")}}
+	assert.NoError(t, HashResource(resource))
+	assert.Equal(t, Hash(`{"a":1000,"b":100.5}`), resource.ContentHash)
+	assert.Equal(t, int64(len(`{"a":1e3,"b":100.50}`)), resource.ContentSize)
+}
+
+func TestCanonicalContentHashIgnoresKeyOrder(t *testing.T) {
+	var first, second interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"a":"x","b":1.0}`), &first))
+	assert.NoError(t, json.Unmarshal([]byte(`{"b":1,"a":"x"}`), &second))
+
+	firstHash, err := CanonicalContentHash(first)
+	assert.NoError(t, err)
+	secondHash, err := CanonicalContentHash(second)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246", firstHash)
+	assert.Equal(t, firstHash, secondHash)
+}
+
+func TestCanonicalContentHashOfNumberOutOfRange(t *testing.T) {
+	hash, err := CanonicalContentHash(map[string]interface{}{"a": json.Number("1E400")})
+	assert.NoError(t, err)
+	assert.Empty(t, hash)
+}
+
+func TestHashCheckMatchesXMLContent(t *testing.T) {
+	passed := false
+	next := func(w http.ResponseWriter, r *http.Request) {
+		passed = true
+	}
+
+	document := `<?xml version="1.0"?><doc xmlns:ft="urn:ft" b="2" a="1"><ft:title>Title</ft:title></doc>`
+
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/xml"}, true, nil)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{
+		UUID:        "a-real-uuid",
+		Content:     []byte(document),
+		ContentType: "application/xml",
+	}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", Filter(next).CheckNativeHash(connection).Build()).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Add(NativeHashHeader, Hash(document))
+
+	router.ServeHTTP(w, req)
+
+	connection.AssertExpectations(t)
+	assert.False(t, passed)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
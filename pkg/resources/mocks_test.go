@@ -2,9 +2,11 @@ package resources
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 
+	"github.com/Financial-Times/nativerw/pkg/db"
 	"github.com/Financial-Times/nativerw/pkg/mapper"
 )
 
@@ -31,9 +33,34 @@ func (m *MockConnection) Delete(collection string, uuidString string, revision i
 	return args.Error(0)
 }
 
-func (m *MockConnection) ReadIDs(ctx context.Context, collection string) (chan string, error) {
-	args := m.Called(ctx, collection)
-	m.CallArgs = []interface{}{ctx, collection}
+func (m *MockConnection) DeleteAll(collection string, uuidString string, audit db.AuditEntry) (revisions []int64, err error) {
+	args := m.Called(collection, uuidString, audit)
+	return args.Get(0).([]int64), args.Error(1)
+}
+
+func (m *MockConnection) DeleteMatching(ctx context.Context, collection string, filter db.RevisionFilter, batchSize int64, audit db.AuditEntry) (int64, error) {
+	args := m.Called(ctx, collection, filter, batchSize, audit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockConnection) DeleteRevisions(ctx context.Context, collection string, uuidString string, revisions []int64) (int64, error) {
+	args := m.Called(ctx, collection, uuidString, revisions)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockConnection) ReadRevisionsMetadata(ctx context.Context, collection string, uuidString string) (res []*mapper.Resource, err error) {
+	args := m.Called(ctx, collection, uuidString)
+	return args.Get(0).([]*mapper.Resource), args.Error(1)
+}
+
+func (m *MockConnection) ReadStoredRevisions(ctx context.Context, collection string, uuidString string) (res []db.StoredRevision, err error) {
+	args := m.Called(ctx, collection, uuidString)
+	return args.Get(0).([]db.StoredRevision), args.Error(1)
+}
+
+func (m *MockConnection) ReadIDs(ctx context.Context, collection string, includeDeleted bool) (chan string, error) {
+	args := m.Called(ctx, collection, includeDeleted)
+	m.CallArgs = []interface{}{ctx, collection, includeDeleted}
 	return args.Get(0).(chan string), args.Error(1)
 }
 
@@ -42,11 +69,26 @@ func (m *MockConnection) Write(collection string, resource *mapper.Resource) err
 	return args.Error(0)
 }
 
+func (m *MockConnection) WriteConditionally(collection string, resource *mapper.Resource, precondition db.Precondition) error {
+	args := m.Called(collection, resource, precondition)
+	return args.Error(0)
+}
+
 func (m *MockConnection) Read(collection string, uuidString string) (res *mapper.Resource, found bool, err error) {
 	args := m.Called(collection, uuidString)
 	return args.Get(0).(*mapper.Resource), args.Bool(1), args.Error(2)
 }
 
+func (m *MockConnection) ReadMetadata(collection string, uuidString string) (res *mapper.Resource, found bool, err error) {
+	args := m.Called(collection, uuidString)
+	return args.Get(0).(*mapper.Resource), args.Bool(1), args.Error(2)
+}
+
+func (m *MockConnection) ReadAsOf(collection string, uuidString string, asOf int64) (res *mapper.Resource, found bool, err error) {
+	args := m.Called(collection, uuidString, asOf)
+	return args.Get(0).(*mapper.Resource), args.Bool(1), args.Error(2)
+}
+
 func (m *MockConnection) ReadSingleRevision(collection string, uuidString string, revision int64) (res *mapper.Resource, err error) {
 	args := m.Called(collection, uuidString, revision)
 	return args.Get(0).(*mapper.Resource), args.Error(1)
@@ -57,11 +99,31 @@ func (m *MockConnection) ReadRevisions(collection string, uuidString string) (re
 	return args.Get(0).([]int64), args.Error(1)
 }
 
+func (m *MockConnection) ReadRevisionsPage(collection string, uuidString string, page db.RevisionPage) (res []*mapper.Resource, more bool, err error) {
+	args := m.Called(collection, uuidString, page)
+	return args.Get(0).([]*mapper.Resource), args.Bool(1), args.Error(2)
+}
+
 func (m *MockConnection) Count(collection string, uuidString string, contentRevision int64) (count int64, err error) {
 	args := m.Called(collection, uuidString, contentRevision)
 	return int64(args.Int(0)), args.Error(1)
 }
 
+func (m *MockConnection) BackfillHashes(ctx context.Context, collection string, hash func(resource *mapper.Resource) error) (int64, error) {
+	args := m.Called(ctx, collection, hash)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockConnection) AcquireLease(ctx context.Context, name string, holder string, ttl time.Duration) (bool, error) {
+	args := m.Called(ctx, name, holder, ttl)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockConnection) ReleaseLease(ctx context.Context, name string, holder string, expires time.Time) error {
+	args := m.Called(ctx, name, holder, expires)
+	return args.Error(0)
+}
+
 func (m *MockConnection) Ping() error {
 	m.Called()
 	return nil
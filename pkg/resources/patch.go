@@ -2,7 +2,9 @@ package resources
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"reflect"
 	"strconv"
@@ -11,10 +13,22 @@ import (
 
 	"github.com/Financial-Times/go-logger"
 	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/jsonpatch"
 	"github.com/Financial-Times/nativerw/pkg/mapper"
 	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
 )
 
+const (
+	jsonPatchContentType  = "application/json-patch+json"
+	mergePatchContentType = "application/merge-patch+json"
+)
+
+// errNotAnObject is returned when a patch leaves a content which is not a JSON object, which could not be stored
+var errNotAnObject = errors.New("the patched content is not a JSON object")
+
+// maxPatchAttempts bounds how many times a patch is re-applied when another writer updates the same document concurrently
+const maxPatchAttempts = 3
+
 func PatchContent(connection db.Connection, ts TimestampCreator) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
@@ -24,23 +38,10 @@ func PatchContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 		resourceID := mux.Vars(r)["resource"]
 		schemaVersion := r.Header.Get(SchemaVersionHeader)
 		contentRevision := ts.CreateTimestamp()
+		precondition := preconditionFromRequest(r)
 
-		resource, found, err := connection.Read(collectionID, resourceID)
-		if err != nil {
-			msg := "Reading from mongoDB failed."
-			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
-			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
-			return
-		}
-
-		if !found {
-			msg := fmt.Sprintf("Could not update resource, not found, collection= %v, id= %v", collectionID, resourceID)
-			logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
-
-			w.Header().Add("Content-Type", "application/json")
-			respBody, _ := json.Marshal(map[string]string{"message": msg})
-			w.WriteHeader(http.StatusNotFound)
-			fmt.Fprint(w, string(respBody))
+		resource, ok := readResourceToPatch(w, connection, collectionID, resourceID, precondition, tid)
+		if !ok {
 			return
 		}
 
@@ -48,46 +49,148 @@ func PatchContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 		if contentTypeHeader == "" {
 			contentTypeHeader = resource.ContentType
 		}
-		inMapper, err := mapper.InMapperForContentType(contentTypeHeader)
-		if err != nil {
-			msg := "Unsupported content-type"
-			logger.
-				WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
-				WithUUID(resourceID).
-				WithError(err).
-				Error(msg)
-			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
-			return
-		}
-
 		originSystemIDHeader := extractAttrFromHeader(r, "Origin-System-Id", "", tid, resourceID)
-		content, err := inMapper(r.Body)
-		if err != nil {
-			msg := "Extracting content from HTTP body failed"
-			logger.
-				WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
-				WithUUID(resourceID).
-				WithError(err).
-				Error(msg)
-			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
-			return
+
+		var patcher contentPatcher
+		switch patchMediaType(contentTypeHeader) {
+		case jsonPatchContentType:
+			// a JSON Patch document describes changes to the stored content, which keeps its own content type
+			contentTypeHeader = resource.ContentType
+			patch, err := jsonpatch.Decode(r.Body)
+			if err != nil {
+				msg := "Extracting JSON Patch from HTTP body failed"
+				logger.
+					WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(err).
+					Error(msg)
+				writePatchError(w, msg, err, bodyErrorStatus(err))
+				return
+			}
+			patcher = jsonPatcher(patch)
+		case mergePatchContentType:
+			contentTypeHeader = resource.ContentType
+			var patch interface{}
+			decoder := json.NewDecoder(r.Body)
+			decoder.UseNumber()
+			if err := decoder.Decode(&patch); err != nil {
+				msg := "Extracting JSON Merge Patch from HTTP body failed"
+				logger.
+					WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(err).
+					Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), bodyErrorStatus(err))
+				return
+			}
+			patcher = func(original interface{}) (interface{}, error) {
+				// a merge patch which is not an object replaces the whole content
+				if _, isObject := patch.(map[string]interface{}); !isObject {
+					return nil, errNotAnObject
+				}
+				return jsonpatch.MergePatch(original, patch), nil
+			}
+		default:
+			if !mapper.IsJSON(contentTypeHeader) {
+				msg := "Only JSON content can be patched"
+				logger.
+					WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, contentTypeHeader), http.StatusUnsupportedMediaType)
+				return
+			}
+
+			inMapper, err := mapper.InMapperForContentType(contentTypeHeader)
+			if err != nil {
+				msg := "Unsupported content-type"
+				logger.
+					WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(err).
+					Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+				return
+			}
+
+			content, err := inMapper(r.Body)
+			if err != nil {
+				msg := "Extracting content from HTTP body failed"
+				logger.
+					WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(err).
+					Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), bodyErrorStatus(err))
+				return
+			}
+			patcher = mergePatcher(content)
 		}
 
-		originalC, _ := resource.Content.(map[string]interface{})
-		PatchC, _ := content.(map[string]interface{})
-		patchResult := mergeContent(PatchC, originalC)
-		resource.Content = patchResult
-
-		wrappedContent := mapper.Wrap(patchResult, resourceID, contentTypeHeader, originSystemIDHeader, schemaVersion, contentRevision)
-		if errWrite := connection.Write(collectionID, wrappedContent); errWrite != nil {
-			msg := "Writing to mongoDB failed"
-			logger.
-				WithMonitoringEvent("UpdatedToNative", tid, contentTypeHeader).
-				WithUUID(resourceID).
-				WithError(errWrite).
-				Error(msg)
-			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, errWrite), http.StatusInternalServerError)
-			return
+		var written *mapper.Resource
+		for attempt := 1; ; attempt++ {
+			patchResult, err := patcher(resource.Content)
+			if err != nil {
+				msg := "Applying the patch failed"
+				logger.
+					WithMonitoringEvent("UpdatedToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(err).
+					Warn(msg)
+				status := http.StatusUnprocessableEntity
+				if errors.Is(err, jsonpatch.ErrTestFailed) {
+					status = http.StatusConflict
+				}
+				writePatchError(w, msg, err, status)
+				return
+			}
+			resource.Content = patchResult
+
+			// the patch is only written if nobody stored a newer revision since the original was read
+			ifLatest := db.IfLatest(resource.ContentRevision)
+			written = mapper.Wrap(patchResult, resourceID, contentTypeHeader, originSystemIDHeader, schemaVersion, contentRevision)
+			errWrite := writeResource(connection, collectionID, written, &ifLatest)
+			if errWrite == nil {
+				break
+			}
+
+			if errors.Is(errWrite, db.ErrContentTooLarge) {
+				msg := "Content too large"
+				logger.
+					WithMonitoringEvent("UpdatedToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(errWrite).
+					Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, errWrite), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			if !errors.Is(errWrite, db.ErrPreconditionFailed) {
+				msg := "Writing to mongoDB failed"
+				logger.
+					WithMonitoringEvent("UpdatedToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(errWrite).
+					Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, errWrite), http.StatusInternalServerError)
+				return
+			}
+
+			if precondition != nil || attempt == maxPatchAttempts {
+				msg := "Precondition failed, the resource has been updated concurrently"
+				logger.
+					WithMonitoringEvent("UpdatedToNative", tid, contentTypeHeader).
+					WithUUID(resourceID).
+					WithError(errWrite).
+					Warn(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, errWrite), http.StatusPreconditionFailed)
+				return
+			}
+
+			resource, ok = readResourceToPatch(w, connection, collectionID, resourceID, precondition, tid)
+			if !ok {
+				return
+			}
 		}
 
 		logger.WithMonitoringEvent("UpdatedToNative", tid, contentTypeHeader).
@@ -109,7 +212,8 @@ func PatchContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 		w.Header().Add("Content-Type", contentTypeHeader)
 		w.Header().Add("Origin-System-Id", resource.OriginSystemID)
 		w.Header().Add(SchemaVersionHeader, schemaVersion)
-		w.Header().Add(ContentRevisionHeader, strconv.FormatInt(resource.ContentRevision, 10))
+		w.Header().Add(ContentRevisionHeader, strconv.FormatInt(contentRevision, 10))
+		setRevisionHeaders(w, written)
 		err = om(w, resource)
 		if err != nil {
 			msg := fmt.Sprintf("Unable to extract native content from resource with id %v. %v", resourceID, err.Error())
@@ -121,6 +225,111 @@ func PatchContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 	}
 }
 
+// contentPatcher applies a decoded patch document to the content of the latest revision
+type contentPatcher func(original interface{}) (interface{}, error)
+
+// jsonPatcher applies a JSON Patch, rejecting the patches which leave a content that is not an object.
+// Only the operations on the whole document can change its type, so the last of them is reported as failing.
+func jsonPatcher(patch jsonpatch.Patch) contentPatcher {
+	return func(original interface{}) (interface{}, error) {
+		result, err := patch.Apply(original)
+		if err != nil {
+			return nil, err
+		}
+		if _, isObject := result.(map[string]interface{}); isObject {
+			return result, nil
+		}
+
+		opErr := &jsonpatch.OperationError{Err: errNotAnObject}
+		for i, op := range patch {
+			if op.Path == "" && op.Op != "test" {
+				opErr.Index, opErr.Op, opErr.Path = i, op.Op, op.Path
+			}
+		}
+		return nil, opErr
+	}
+}
+
+func mergePatcher(content interface{}) contentPatcher {
+	return func(original interface{}) (interface{}, error) {
+		originalC, _ := original.(map[string]interface{})
+		PatchC, _ := content.(map[string]interface{})
+		return mergeContent(PatchC, originalC), nil
+	}
+}
+
+// patchMediaType returns the media type of the patch document, without parameters
+func patchMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// writePatchError reports a patch which could not be applied, including the index of the failing operation if known
+func writePatchError(w http.ResponseWriter, msg string, err error, status int) {
+	body := struct {
+		Message   string `json:"message"`
+		Operation *int   `json:"operation,omitempty"`
+	}{Message: fmt.Sprintf("%s: %v", msg, err)}
+
+	var opErr *jsonpatch.OperationError
+	if errors.As(err, &opErr) {
+		body.Operation = &opErr.Index
+	}
+
+	data, _ := json.Marshal(body)
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(data); err != nil {
+		logger.WithError(err).Error("could not build response JSON body")
+	}
+}
+
+// readResourceToPatch reads the latest revision and checks it against the request precondition, writing the error response if needed
+func readResourceToPatch(w http.ResponseWriter, connection db.Connection, collectionID, resourceID string, precondition *db.Precondition, tid string) (*mapper.Resource, bool) {
+	resource, found, err := connection.Read(collectionID, resourceID)
+	if err != nil {
+		msg := "Reading from mongoDB failed."
+		logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if !found {
+		msg := fmt.Sprintf("Could not update resource, not found, collection= %v, id= %v", collectionID, resourceID)
+		logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+
+		w.Header().Add("Content-Type", "application/json")
+		respBody, _ := json.Marshal(map[string]string{"message": msg})
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, string(respBody))
+		return nil, false
+	}
+
+	if resource.Deleted {
+		writeGone(w, resource, tid)
+		return nil, false
+	}
+
+	if !mapper.IsJSON(resource.ContentType) {
+		msg := fmt.Sprintf("Could not update resource, its content type is not JSON, content-type= %v", resource.ContentType)
+		logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+		writeMessage(w, msg, http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	if precondition != nil && !precondition.SatisfiedBy(resource.ContentRevision, resource.ContentHash, found) {
+		msg := "Precondition failed"
+		logger.WithTransactionID(tid).WithUUID(resourceID).Warn(msg)
+		http.Error(w, msg, http.StatusPreconditionFailed)
+		return nil, false
+	}
+
+	return resource, true
+}
+
 // Rules to modify content :
 // 1- A field in order to be updated/removed must exists in both data sources (patchC, originalC):
 //
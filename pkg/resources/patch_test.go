@@ -13,7 +13,9 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
+	"github.com/Financial-Times/nativerw/pkg/db"
 	"github.com/Financial-Times/nativerw/pkg/mapper"
 )
 
@@ -27,7 +29,7 @@ func TestPatchContent(t *testing.T) {
 	var contentRevision int64 = 1436773875771421417
 
 	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{}, ContentRevision: contentRevision}, true, nil)
-	connection.On("Write", collection, &mapper.Resource{UUID: uuid, Content: updatedContent, ContentType: contentType, ContentRevision: contentRevision}).Return(nil)
+	connection.On("WriteConditionally", collection, hashed(&mapper.Resource{UUID: uuid, Content: updatedContent, ContentType: contentType, ContentRevision: contentRevision}), db.IfLatest(contentRevision)).Return(nil)
 
 	ts := fixedTimestampCreator{}
 
@@ -45,6 +47,27 @@ func TestPatchContent(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestPatchContentWithMixedCaseContentType(t *testing.T) {
+	connection := new(MockConnection)
+	var contentRevision int64 = 1436773875771421417
+
+	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{}, ContentRevision: contentRevision}, true, nil)
+	connection.On("WriteConditionally", "universal-content", hashed(&mapper.Resource{UUID: "a-real-uuid", Content: map[string]interface{}{"body": "updated-data"}, ContentType: "Application/JSON", ContentRevision: contentRevision}), db.IfLatest(contentRevision)).Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods("PATCH")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(`{"body": "updated-data"}`))
+	req.Header.Add("Content-Type", "Application/JSON")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
 func TestShouldNotUpdatePatchContentEmptyRequestBody(t *testing.T) {
 	connection := new(MockConnection)
 	uuid := "a-real-uuid"
@@ -55,7 +78,7 @@ func TestShouldNotUpdatePatchContentEmptyRequestBody(t *testing.T) {
 	var contentRevision int64 = 1436773875771421417
 
 	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: existingContent, ContentRevision: contentRevision}, true, nil)
-	connection.On("Write", collection, &mapper.Resource{UUID: uuid, Content: existingContent, ContentType: contentType, ContentRevision: contentRevision}).Return(nil)
+	connection.On("WriteConditionally", collection, hashed(&mapper.Resource{UUID: uuid, Content: existingContent, ContentType: contentType, ContentRevision: contentRevision}), db.IfLatest(contentRevision)).Return(nil)
 
 	ts := fixedTimestampCreator{}
 
@@ -84,13 +107,14 @@ func TestPatchContentWithCharsetDirective(t *testing.T) {
 	var contentRevision int64 = 1436773875771421417
 
 	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{}, ContentRevision: contentRevision}, true, nil)
-	connection.On("Write",
+	connection.On("WriteConditionally",
 		collection,
-		&mapper.Resource{
+		hashed(&mapper.Resource{
 			UUID:            uuid,
 			Content:         content,
 			ContentType:     contentTypeWithCharset,
-			ContentRevision: contentRevision}).
+			ContentRevision: contentRevision}),
+		db.IfLatest(contentRevision)).
 		Return(nil)
 
 	ts := fixedTimestampCreator{}
@@ -119,7 +143,7 @@ func TestPatchFailedOnWrite(t *testing.T) {
 	var contentRevision int64 = 1436773875771421417
 
 	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{}, ContentRevision: contentRevision}, true, nil)
-	connection.On("Write", collection, &mapper.Resource{UUID: uuid, Content: content, ContentType: contentType, ContentRevision: contentRevision}).Return(errors.New("i failed"))
+	connection.On("WriteConditionally", collection, hashed(&mapper.Resource{UUID: uuid, Content: content, ContentType: contentType, ContentRevision: contentRevision}), db.IfLatest(contentRevision)).Return(errors.New("i failed"))
 
 	ts := fixedTimestampCreator{}
 
@@ -235,7 +259,7 @@ func TestDefaultContentType(t *testing.T) {
 	var contentRevision int64 = 1436773875771421417
 
 	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{}, ContentRevision: contentRevision}, true, nil)
-	connection.On("Write", collection, &mapper.Resource{UUID: uuid, Content: updatedContent, ContentType: contentType, ContentRevision: contentRevision}).Return(nil)
+	connection.On("WriteConditionally", collection, hashed(&mapper.Resource{UUID: uuid, Content: updatedContent, ContentType: contentType, ContentRevision: contentRevision}), db.IfLatest(contentRevision)).Return(nil)
 
 	ts := fixedTimestampCreator{}
 
@@ -398,3 +422,406 @@ func TestPatchContentReflection(t *testing.T) {
 		}
 	}
 }
+
+func TestPatchContentRetriesOnConcurrentUpdate(t *testing.T) {
+	connection := new(MockConnection)
+	uuid := "a-real-uuid"
+	collection := "universal-content"
+	contentType := "application/json"
+	httpMethod := "PATCH"
+	var contentRevision int64 = 1436773875771421417
+	var staleRevision int64 = 1436773875771421000
+	var concurrentRevision int64 = 1436773875771421200
+
+	connection.On("Read", collection, uuid).
+		Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{"title": "stale"}, ContentRevision: staleRevision}, true, nil).
+		Once()
+	connection.On("Read", collection, uuid).
+		Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{"title": "concurrent"}, ContentRevision: concurrentRevision}, true, nil).
+		Once()
+	connection.On("WriteConditionally", collection,
+		hashed(&mapper.Resource{UUID: uuid, Content: map[string]interface{}{"title": "stale", "body": "updated-data"}, ContentType: contentType, ContentRevision: contentRevision}),
+		db.IfLatest(staleRevision)).
+		Return(db.ErrPreconditionFailed)
+	connection.On("WriteConditionally", collection,
+		hashed(&mapper.Resource{UUID: uuid, Content: map[string]interface{}{"title": "concurrent", "body": "updated-data"}, ContentType: contentType, ContentRevision: contentRevision}),
+		db.IfLatest(concurrentRevision)).
+		Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods(httpMethod)
+
+	w := httptest.NewRecorder()
+	path := fmt.Sprintf("/%s/%s", collection, uuid)
+	req, _ := http.NewRequest(httpMethod, path, strings.NewReader(`{"body": "updated-data"}`))
+
+	req.Header.Add("Content-Type", contentType)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"`+Hash(`{"body":"updated-data","title":"concurrent"}`)+`"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"title": "concurrent", "body": "updated-data"}`, w.Body.String())
+}
+
+func TestPatchContentIfMatchFailed(t *testing.T) {
+	connection := new(MockConnection)
+	uuid := "a-real-uuid"
+	collection := "universal-content"
+	contentType := "application/json"
+	httpMethod := "PATCH"
+
+	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{}, ContentRevision: 2}, true, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods(httpMethod)
+
+	w := httptest.NewRecorder()
+	path := fmt.Sprintf("/%s/%s", collection, uuid)
+	req, _ := http.NewRequest(httpMethod, path, strings.NewReader(`{"body": "updated-data"}`))
+
+	req.Header.Add("Content-Type", contentType)
+	req.Header.Add("If-Match", `"1"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestPatchContentIfMatchConcurrentUpdate(t *testing.T) {
+	connection := new(MockConnection)
+	uuid := "a-real-uuid"
+	collection := "universal-content"
+	contentType := "application/json"
+	httpMethod := "PATCH"
+	var contentRevision int64 = 1436773875771421417
+
+	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: map[string]interface{}{}, ContentRevision: 1}, true, nil)
+	connection.On("WriteConditionally", collection,
+		hashed(&mapper.Resource{UUID: uuid, Content: map[string]interface{}{"body": "updated-data"}, ContentType: contentType, ContentRevision: contentRevision}),
+		db.IfLatest(1)).
+		Return(db.ErrPreconditionFailed)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods(httpMethod)
+
+	w := httptest.NewRecorder()
+	path := fmt.Sprintf("/%s/%s", collection, uuid)
+	req, _ := http.NewRequest(httpMethod, path, strings.NewReader(`{"body": "updated-data"}`))
+
+	req.Header.Add("Content-Type", contentType)
+	req.Header.Add("If-Match", `"1"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	connection.AssertNumberOfCalls(t, "Read", 1)
+}
+
+func TestPatchContentWithJSONPatch(t *testing.T) {
+	connection := new(MockConnection)
+	uuid := "a-real-uuid"
+	collection := "universal-content"
+	contentType := "application/vnd.ft-upp-article+json"
+	httpMethod := "PATCH"
+	var contentRevision int64 = 1436773875771421417
+
+	original := map[string]interface{}{"title": "Title", "brands": []interface{}{"Lex", "Markets"}, "count": float64(1)}
+	patched := map[string]interface{}{"title": "Title", "brands": []interface{}{"Markets"}, "count": "one"}
+	connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: original, ContentRevision: 1}, true, nil)
+	connection.On("WriteConditionally", collection, hashed(&mapper.Resource{UUID: uuid, Content: patched, ContentType: contentType, ContentRevision: contentRevision}), db.IfLatest(1)).Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods(httpMethod)
+
+	w := httptest.NewRecorder()
+	path := fmt.Sprintf("/%s/%s", collection, uuid)
+	req, _ := http.NewRequest(httpMethod, path, strings.NewReader(`[
+		{"op": "test", "path": "/title", "value": "Title"},
+		{"op": "remove", "path": "/brands/0"},
+		{"op": "replace", "path": "/count", "value": "one"}
+	]`))
+
+	req.Header.Add("Content-Type", "application/json-patch+json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, contentType, w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"title": "Title", "brands": ["Markets"], "count": "one"}`, w.Body.String())
+}
+
+func TestPatchContentWithFailingJSONPatch(t *testing.T) {
+	var tests = []struct {
+		name           string
+		patch          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "test operation fails",
+			patch:          `[{"op": "replace", "path": "/title", "value": "New"}, {"op": "test", "path": "/body", "value": "other"}]`,
+			expectedStatus: http.StatusConflict,
+			expectedBody:   `{"message": "Applying the patch failed: operation 1 (test /body): test failed", "operation": 1}`,
+		},
+		{
+			name:           "path is missing",
+			patch:          `[{"op": "replace", "path": "/missing", "value": "New"}]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"message": "Applying the patch failed: operation 0 (replace /missing): path not found", "operation": 0}`,
+		},
+		{
+			name:           "content replaced by an array",
+			patch:          `[{"op": "replace", "path": "/title", "value": "New"}, {"op": "replace", "path": "", "value": [1, 2]}]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"message": "Applying the patch failed: operation 1 (replace ): the patched content is not a JSON object", "operation": 1}`,
+		},
+		{
+			name:           "content replaced by a scalar",
+			patch:          `[{"op": "add", "path": "", "value": "text"}, {"op": "test", "path": "", "value": "text"}]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBody:   `{"message": "Applying the patch failed: operation 0 (add ): the patched content is not a JSON object", "operation": 0}`,
+		},
+		{
+			name:           "unknown operation",
+			patch:          `[{"op": "merge", "path": "/title"}]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message": "Extracting JSON Patch from HTTP body failed: operation 0 (merge /title): invalid patch operation", "operation": 0}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			connection := new(MockConnection)
+			connection.On("Read", "universal-content", "a-real-uuid").
+				Return(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{"title": "Title", "body": "body"}, ContentRevision: 1}, true, nil)
+
+			ts := fixedTimestampCreator{}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods("PATCH")
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(test.patch))
+			req.Header.Add("Content-Type", "application/json-patch+json")
+
+			router.ServeHTTP(w, req)
+			connection.AssertExpectations(t)
+			assert.Equal(t, test.expectedStatus, w.Code)
+			assert.JSONEq(t, test.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestPatchContentConformance(t *testing.T) {
+	var tests = []struct {
+		name        string
+		contentType string
+		original    string
+		patch       string
+		expected    string
+	}{
+		// RFC 7396, Appendix A, for documents which are objects
+		{"merge: replace member", mergePatchContentType, `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"merge: add member", mergePatchContentType, `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"merge: remove only member", mergePatchContentType, `{"a":"b"}`, `{"a":null}`, `{}`},
+		{"merge: remove member", mergePatchContentType, `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"merge: array to string", mergePatchContentType, `{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"merge: string to array", mergePatchContentType, `{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{"merge: nested objects", mergePatchContentType, `{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{"merge: replace array", mergePatchContentType, `{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"merge: keep null members", mergePatchContentType, `{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{"merge: empty objects are kept", mergePatchContentType, `{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		// patches which are not objects replace the whole document, which must stay an object
+		{"merge: null patch is rejected", mergePatchContentType, `{"a":"b"}`, `null`, ""},
+		{"merge: string patch is rejected", mergePatchContentType, `{"a":"b"}`, `"c"`, ""},
+		{"merge: array patch is rejected", mergePatchContentType, `{"a":"b"}`, `["c"]`, ""},
+		// RFC 6902, Appendix A
+		{"patch: add member", jsonPatchContentType, `{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{"patch: add array element", jsonPatchContentType, `{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{"patch: remove member", jsonPatchContentType, `{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{"patch: remove array element", jsonPatchContentType, `{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{"patch: replace value", jsonPatchContentType, `{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{"patch: move value", jsonPatchContentType, `{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{"patch: move array element", jsonPatchContentType, `{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{"patch: add nested object", jsonPatchContentType, `{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{"patch: append array value", jsonPatchContentType, `{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var original map[string]interface{}
+			err := json.Unmarshal([]byte(test.original), &original)
+			assert.NoError(t, err)
+
+			connection := new(MockConnection)
+			connection.On("Read", "universal-content", "a-real-uuid").
+				Return(&mapper.Resource{ContentType: "application/json", Content: original, ContentRevision: 1}, true, nil)
+			if test.expected != "" {
+				connection.On("WriteConditionally", "universal-content", mock.AnythingOfType("*mapper.Resource"), db.IfLatest(1)).
+					Return(nil)
+			}
+
+			ts := fixedTimestampCreator{}
+
+			router := mux.NewRouter()
+			router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods("PATCH")
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(test.patch))
+			req.Header.Add("Content-Type", test.contentType)
+
+			router.ServeHTTP(w, req)
+			connection.AssertExpectations(t)
+			if test.expected == "" {
+				connection.AssertNotCalled(t, "WriteConditionally", mock.Anything, mock.Anything, mock.Anything)
+				assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+				return
+			}
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+			assert.JSONEq(t, test.expected, w.Body.String())
+
+			written := connection.Calls[1].Arguments.Get(1).(*mapper.Resource)
+			assert.Equal(t, "application/json", written.ContentType)
+		})
+	}
+}
+
+func TestPatchDeletedContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 2, Deleted: true}, true, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &ts)).Methods("PATCH")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(`{"body": "updated-data"}`))
+	req.Header.Add("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, "2", w.Header().Get(ContentRevisionHeader))
+}
+
+func TestPatchContentKeepsNumbers(t *testing.T) {
+	uuid := "a-real-uuid"
+	collection := "universal-content"
+	contentType := "application/json"
+	var contentRevision int64 = 1436773875771421417
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+	}{
+		{name: "nativerw merge", contentType: contentType, patch: `{"price":2.50000000000000000001}`},
+		{name: "JSON Merge Patch", contentType: mergePatchContentType, patch: `{"price":2.50000000000000000001}`},
+		{name: "JSON Patch", contentType: jsonPatchContentType, patch: `[{"op":"replace","path":"/price","value":2.50000000000000000001}]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the numbers are read from the store as they were written
+			original := map[string]interface{}{"id": json.Number("9007199254740993"), "price": json.Number("3.141592653589793238462643383279")}
+			patched := map[string]interface{}{"id": json.Number("9007199254740993"), "price": json.Number("2.50000000000000000001")}
+
+			connection := new(MockConnection)
+			connection.On("Read", collection, uuid).Return(&mapper.Resource{ContentType: contentType, Content: original, ContentRevision: 1}, true, nil)
+			connection.On("WriteConditionally", collection, hashed(&mapper.Resource{UUID: uuid, Content: patched, ContentType: contentType, ContentRevision: contentRevision}), db.IfLatest(1)).Return(nil)
+
+			router := mux.NewRouter()
+			router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &fixedTimestampCreator{})).Methods("PATCH")
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PATCH", fmt.Sprintf("/%s/%s", collection, uuid), strings.NewReader(test.patch))
+			req.Header.Add("Content-Type", test.contentType)
+
+			router.ServeHTTP(w, req)
+			connection.AssertExpectations(t)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, `{"id":9007199254740993,"price":2.50000000000000000001}`, strings.TrimSpace(w.Body.String()))
+		})
+	}
+}
+
+func TestPatchBinaryContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "text/plain; charset=utf-8", Content: []byte("plain text"), ContentRevision: 1}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &fixedTimestampCreator{})).Methods("PATCH")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(`{"title":"Title"}`))
+	req.Header.Add("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertNotCalled(t, "WriteConditionally", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestPatchXMLContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/xml", Content: []byte(`<doc><title>Title</title></doc>`), ContentRevision: 1}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &fixedTimestampCreator{})).Methods("PATCH")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(`<doc><title>New title</title></doc>`))
+	req.Header.Add("Content-Type", "application/xml")
+
+	router.ServeHTTP(w, req)
+	connection.AssertNotCalled(t, "WriteConditionally", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestPatchContentWithBinaryBody(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{"title": "Title"}, ContentRevision: 1}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &fixedTimestampCreator{})).Methods("PATCH")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(`title=Title`))
+	req.Header.Add("Content-Type", "text/plain")
+
+	router.ServeHTTP(w, req)
+	connection.AssertNotCalled(t, "WriteConditionally", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+}
+
+func TestPatchContentTooLarge(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{}, ContentRevision: 1}, true, nil)
+	connection.On("WriteConditionally", "universal-content", mock.Anything, db.IfLatest(1)).Return(db.ErrContentTooLarge)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", PatchContent(connection, &fixedTimestampCreator{})).Methods("PATCH")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("PATCH", "/universal-content/a-real-uuid", strings.NewReader(`{"body":"a large body"}`))
+	req.Header.Add("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -1,9 +1,11 @@
 package resources
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 
@@ -12,7 +14,11 @@ import (
 	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
 )
 
-// PurgeContent deletes the given resource from the given collection
+// RequestedByHeader identifies who asked for an erasure, for the audit trail
+const RequestedByHeader = "X-Requested-By"
+
+// PurgeContent deletes the given resource from the given collection.
+// With ?dryRun=true it only answers with the revision which would be deleted.
 func PurgeContent(connection db.Connection) func(writer http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
@@ -37,6 +43,23 @@ func PurgeContent(connection db.Connection) func(writer http.ResponseWriter, req
 
 		contentTypeHeader := extractAttrFromHeader(r, "Content-Type", "application/json", tid, uuid)
 
+		if isDryRun(r) {
+			count, err := connection.Count(collectionID, uuid, revision)
+			if err != nil {
+				msg := "Reading from mongoDB failed."
+				logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+				http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+				return
+			}
+
+			revisions := []int64{}
+			if count > 0 {
+				revisions = append(revisions, revision)
+			}
+			writePurgeDryRun(w, collectionID, uuid, revisions, tid)
+			return
+		}
+
 		if err := connection.Delete(collectionID, uuid, revision); err != nil {
 			msg := "Deleting from mongoDB failed"
 			logger.WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).WithUUID(uuid).WithError(err).Error(msg)
@@ -47,3 +70,81 @@ func PurgeContent(connection db.Connection) func(writer http.ResponseWriter, req
 		logger.WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).WithUUID(uuid).Info("Successfully deleted")
 	}
 }
+
+// PurgeAllContent deletes every revision of the given resource from the given collection, and records the erasure in the audit trail.
+// With ?dryRun=true it only answers with the revisions which would be deleted.
+func PurgeAllContent(connection db.Connection, ts TimestampCreator) func(writer http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+
+		collectionID := mux.Vars(r)["collection"]
+		uuid := mux.Vars(r)["resource"]
+		requestedBy := r.Header.Get(RequestedByHeader)
+
+		contentTypeHeader := extractAttrFromHeader(r, "Content-Type", "application/json", tid, uuid)
+
+		if isDryRun(r) {
+			revisions, err := connection.ReadRevisions(collectionID, uuid)
+			if err != nil {
+				msg := "Reading from mongoDB failed."
+				logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+				http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+				return
+			}
+			writePurgeDryRun(w, collectionID, uuid, revisions, tid)
+			return
+		}
+
+		entry := db.AuditEntry{
+			Action:        "purge",
+			Collection:    collectionID,
+			UUID:          uuid,
+			RequestedBy:   requestedBy,
+			TransactionID: tid,
+			Time:          time.Unix(0, ts.CreateTimestamp()).UTC(),
+		}
+		revisions, err := connection.DeleteAll(collectionID, uuid, entry)
+		if err != nil {
+			msg := "Deleting from mongoDB failed"
+			logger.WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).WithUUID(uuid).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusInternalServerError)
+			return
+		}
+
+		if len(revisions) == 0 {
+			msg := fmt.Sprintf("Resource not found, collection=%v, id=%v", collectionID, uuid)
+			logger.WithTransactionID(tid).WithUUID(uuid).Info(msg)
+			writeMessage(w, msg, http.StatusNotFound)
+			return
+		}
+
+		respBody, _ := json.Marshal(revisions)
+		w.Header().Add("Content-Type", "application/json")
+		fmt.Fprint(w, string(respBody))
+
+		logger.WithMonitoringEvent("SaveToNative", tid, contentTypeHeader).
+			WithUUID(uuid).
+			WithField("collection", collectionID).
+			WithField("requested-by", requestedBy).
+			WithField("revisions", len(revisions)).
+			Info("Successfully deleted all revisions")
+	}
+}
+
+// writePurgeDryRun answers with the revisions a purge would delete, without deleting anything
+func writePurgeDryRun(w http.ResponseWriter, collectionID, uuid string, revisions []int64, tid string) {
+	if len(revisions) == 0 {
+		msg := fmt.Sprintf("Resource not found, collection=%v, id=%v", collectionID, uuid)
+		logger.WithTransactionID(tid).WithUUID(uuid).Info(msg)
+		writeMessage(w, msg, http.StatusNotFound)
+		return
+	}
+
+	logger.WithTransactionID(tid).WithUUID(uuid).WithField("revisions", revisions).Info("Purge dry run, nothing deleted")
+
+	respBody, _ := json.Marshal(revisions)
+	w.Header().Add("Content-Type", "application/json")
+	fmt.Fprint(w, string(respBody))
+}
@@ -6,9 +6,13 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
 )
 
 func TestDeleteContent(t *testing.T) {
@@ -40,3 +44,115 @@ func TestFailedDelete(t *testing.T) {
 	connection.AssertExpectations(t)
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
+
+func TestPurgeAllContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("DeleteAll", "universal-content", "a-real-uuid", db.AuditEntry{
+		Action:        "purge",
+		Collection:    "universal-content",
+		UUID:          "a-real-uuid",
+		RequestedBy:   "legal@example.com",
+		TransactionID: "tid_erasure",
+		Time:          time.Unix(0, 1436773875771421417).UTC(),
+	}).Return([]int64{1, 2, 3}, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/purge/{resource}", PurgeAllContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+	req.Header.Add(RequestedByHeader, "legal@example.com")
+	req.Header.Add("X-Request-Id", "tid_erasure")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[1,2,3]`, w.Body.String())
+}
+
+func TestPurgeAllContentNotFound(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("DeleteAll", "universal-content", "a-real-uuid", mock.AnythingOfType("db.AuditEntry")).Return([]int64{}, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/purge/{resource}", PurgeAllContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPurgeAllContentFails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("DeleteAll", "universal-content", "a-real-uuid", mock.AnythingOfType("db.AuditEntry")).Return([]int64(nil), errors.New("i failed"))
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/purge/{resource}", PurgeAllContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestPurgeContentDryRun(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(123)).Return(1, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/purge/{resource}/{revision}", PurgeContent(connection)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid/123?dryRun=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Delete", "universal-content", "a-real-uuid", int64(123))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[123]`, w.Body.String())
+}
+
+func TestPurgeContentDryRunNotFound(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(123)).Return(0, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/purge/{resource}/{revision}", PurgeContent(connection)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid/123?dryRun=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestPurgeAllContentDryRun(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisions", "universal-content", "a-real-uuid").Return([]int64{1, 2, 3}, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/purge/{resource}", PurgeAllContent(connection, &ts)).Methods("DELETE")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/universal-content/purge/a-real-uuid?dryRun=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "DeleteAll", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[1,2,3]`, w.Body.String())
+}
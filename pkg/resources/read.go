@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -27,7 +28,49 @@ func ReadContent(connection db.Connection) func(w http.ResponseWriter, r *http.R
 		resourceID := vars["resource"]
 		collection := vars["collection"]
 
-		resource, found, err := connection.Read(collection, resourceID)
+		asOfStr := r.URL.Query().Get("asOf")
+		asOf, err := parseAsOf(asOfStr)
+		if err != nil {
+			msg := "Invalid asOf, expected an RFC 3339 date-time or nanoseconds since the epoch"
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		hashAlgorithm, err := hashAlgorithmFromRequest(r)
+		if err != nil {
+			msg := "Invalid native hash algorithm"
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		if asOfStr == "" && isConditionalRead(r) {
+			metadata, found, err := connection.ReadMetadata(collection, resourceID)
+			if err != nil {
+				msg := "Reading from mongoDB failed."
+				logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+				http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+				return
+			}
+
+			if found && !metadata.Deleted {
+				representation, acceptable := negotiatedRepresentation(metadata.ContentType, r.Header.Get("Accept"))
+				if acceptable && notModified(r, metadata, representation) {
+					logger.WithTransactionID(tid).WithUUID(resourceID).Info("Native content not modified")
+					writeNotModified(w, metadata, representation)
+					return
+				}
+			}
+		}
+
+		var resource *mapper.Resource
+		var found bool
+		if asOfStr != "" {
+			resource, found, err = connection.ReadAsOf(collection, resourceID, asOf)
+		} else {
+			resource, found, err = connection.Read(collection, resourceID)
+		}
 		if err != nil {
 			msg := "Reading from mongoDB failed."
 			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
@@ -46,13 +89,18 @@ func ReadContent(connection db.Connection) func(w http.ResponseWriter, r *http.R
 			return
 		}
 
-		contentTypeHeader := resource.ContentType
-		w.Header().Add("Content-Type", contentTypeHeader)
-		w.Header().Add("Origin-System-Id", resource.OriginSystemID)
-		w.Header().Add(SchemaVersionHeader, resource.SchemaVersion)
-		w.Header().Add(ContentRevisionHeader, strconv.FormatInt(resource.ContentRevision, 10))
+		if resource.Deleted {
+			writeGone(w, resource, tid)
+			return
+		}
 
-		om, err := mapper.OutMapperForContentType(contentTypeHeader)
+		om, contentTypeHeader, err := mapper.NegotiateOutMapper(resource.ContentType, r.Header.Get("Accept"))
+		if errors.Is(err, mapper.ErrNotAcceptable) {
+			msg := fmt.Sprintf("Unable to render a content of type %v as %v", resource.ContentType, r.Header.Get("Accept"))
+			logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+			writeMessage(w, msg, http.StatusNotAcceptable)
+			return
+		}
 		if err != nil {
 			msg := fmt.Sprintf("Unable to handle resource of type %T", resource)
 			logger.WithError(err).WithTransactionID(tid).WithUUID(resourceID).Warn(msg)
@@ -60,6 +108,21 @@ func ReadContent(connection db.Connection) func(w http.ResponseWriter, r *http.R
 			return
 		}
 
+		representation := representationOf(resource.ContentType, contentTypeHeader)
+		if asOfStr != "" && isConditionalRead(r) && notModified(r, resource, representation) {
+			logger.WithTransactionID(tid).WithUUID(resourceID).Info("Native content not modified")
+			writeNotModified(w, resource, representation)
+			return
+		}
+
+		w.Header().Add("Content-Type", contentTypeHeader)
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Origin-System-Id", resource.OriginSystemID)
+		w.Header().Add(SchemaVersionHeader, resource.SchemaVersion)
+		w.Header().Add(ContentRevisionHeader, strconv.FormatInt(resource.ContentRevision, 10))
+		setRepresentationHeaders(w, resource, representation)
+		setNativeHashHeader(w, resource, hashAlgorithm)
+
 		err = om(w, resource)
 		if err != nil {
 			msg := fmt.Sprintf("Unable to extract native content from resource with id %v. %v", resourceID, err.Error())
@@ -71,6 +134,23 @@ func ReadContent(connection db.Connection) func(w http.ResponseWriter, r *http.R
 	}
 }
 
+// parseAsOf parses an instant given either as an RFC 3339 date-time or as nanoseconds since the epoch, like content revisions.
+// It returns zero when the value is not set.
+func parseAsOf(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if nanos, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return nanos, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return 0, err
+	}
+	return t.UnixNano(), nil
+}
+
 // ReadSingleRevision reads the native data for the given id/collection/revision
 func ReadSingleRevision(connection db.Connection) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -94,6 +174,34 @@ func ReadSingleRevision(connection db.Connection) func(w http.ResponseWriter, r
 			return
 		}
 
+		hashAlgorithm, err := hashAlgorithmFromRequest(r)
+		if err != nil {
+			msg := "Invalid native hash algorithm"
+			logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		if isConditionalRead(r) {
+			// revisions are immutable, so the client copy is current as long as the revision still exists and is served in the same representation
+			metadata, _, err := connection.ReadRevisionsPage(collection, uuid, db.RevisionPage{Limit: 1, Before: revision + 1})
+			if err != nil {
+				msg := "Reading from mongoDB failed."
+				logger.WithTransactionID(tid).WithUUID(uuid).WithError(err).Error(msg)
+				http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+				return
+			}
+
+			if len(metadata) > 0 && metadata[0].ContentRevision == revision && !metadata[0].Deleted {
+				representation, acceptable := negotiatedRepresentation(metadata[0].ContentType, r.Header.Get("Accept"))
+				if acceptable && notModified(r, metadata[0], representation) {
+					logger.WithTransactionID(tid).WithUUID(uuid).Info("Native content not modified")
+					writeNotModified(w, metadata[0], representation)
+					return
+				}
+			}
+		}
+
 		resource, err := connection.ReadSingleRevision(collection, uuid, revision)
 		if err != nil {
 			msg := "Reading from mongoDB failed."
@@ -113,13 +221,18 @@ func ReadSingleRevision(connection db.Connection) func(w http.ResponseWriter, r
 			return
 		}
 
-		contentTypeHeader := resource.ContentType
-		w.Header().Add("Content-Type", contentTypeHeader)
-		w.Header().Add("Origin-System-Id", resource.OriginSystemID)
-		w.Header().Add(SchemaVersionHeader, resource.SchemaVersion)
-		w.Header().Add(ContentRevisionHeader, strconv.FormatInt(resource.ContentRevision, 10))
+		if resource.Deleted {
+			writeGone(w, resource, tid)
+			return
+		}
 
-		om, err := mapper.OutMapperForContentType(contentTypeHeader)
+		om, contentTypeHeader, err := mapper.NegotiateOutMapper(resource.ContentType, r.Header.Get("Accept"))
+		if errors.Is(err, mapper.ErrNotAcceptable) {
+			msg := fmt.Sprintf("Unable to render a content of type %v as %v", resource.ContentType, r.Header.Get("Accept"))
+			logger.WithTransactionID(tid).WithUUID(uuid).Info(msg)
+			writeMessage(w, msg, http.StatusNotAcceptable)
+			return
+		}
 		if err != nil {
 			msg := fmt.Sprintf("Unable to handle resource of type %T", resource)
 			logger.WithError(err).WithTransactionID(tid).WithUUID(uuid).Warn(msg)
@@ -127,6 +240,14 @@ func ReadSingleRevision(connection db.Connection) func(w http.ResponseWriter, r
 			return
 		}
 
+		w.Header().Add("Content-Type", contentTypeHeader)
+		w.Header().Add("Vary", "Accept")
+		w.Header().Add("Origin-System-Id", resource.OriginSystemID)
+		w.Header().Add(SchemaVersionHeader, resource.SchemaVersion)
+		w.Header().Add(ContentRevisionHeader, strconv.FormatInt(resource.ContentRevision, 10))
+		setRepresentationHeaders(w, resource, representationOf(resource.ContentType, contentTypeHeader))
+		setNativeHashHeader(w, resource, hashAlgorithm)
+
 		err = om(w, resource)
 		if err != nil {
 			msg := fmt.Sprintf("Unable to extract native content from resource with id %v. %v", uuid, err.Error())
@@ -138,7 +259,7 @@ func ReadSingleRevision(connection db.Connection) func(w http.ResponseWriter, r
 	}
 }
 
-// ReadRevisions returns a list with all the revisions for an uuid
+// ReadRevisions returns a list with all the revisions for an uuid, or a page of them newest first when details or pagination are requested
 func ReadRevisions(connection db.Connection) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
@@ -148,6 +269,11 @@ func ReadRevisions(connection db.Connection) func(w http.ResponseWriter, r *http
 		resourceID := vars["resource"]
 		collection := vars["collection"]
 
+		if isRevisionsPageRequest(r.URL.Query()) {
+			readRevisionsPage(w, r, connection, collection, resourceID, tid)
+			return
+		}
+
 		revisions, err := connection.ReadRevisions(collection, resourceID)
 		if err != nil {
 			msg := "Reading from mongoDB failed."
@@ -191,7 +317,8 @@ func ReadIDs(connection db.Connection) func(w http.ResponseWriter, r *http.Reque
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		ids, err := connection.ReadIDs(ctx, coll)
+		includeDeleted, _ := strconv.ParseBool(r.URL.Query().Get("includeDeleted"))
+		ids, err := connection.ReadIDs(ctx, coll, includeDeleted)
 		if err != nil {
 			msg := fmt.Sprintf(`Failed to read IDs from mongo for %v! "%v"`, coll, err.Error())
 			logger.WithTransactionID(tid).WithError(err).Error(msg)
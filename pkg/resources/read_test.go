@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
+	"github.com/Financial-Times/nativerw/pkg/db"
 	"github.com/Financial-Times/nativerw/pkg/mapper"
 )
 
@@ -19,8 +21,9 @@ func TestReadContent(t *testing.T) {
 	connection.On("Read", "universal-content", "a-real-uuid").
 		Return(
 			&mapper.Resource{
-				ContentType: "application/json",
-				Content:     map[string]interface{}{"uuid": "fake-data"}},
+				ContentType:     "application/json",
+				Content:         map[string]interface{}{"uuid": "fake-data"},
+				ContentRevision: 1436773875771421417},
 			true,
 			nil)
 
@@ -35,6 +38,32 @@ func TestReadContent(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 	assert.Equal(t, `{"uuid":"fake-data"}`, strings.TrimSpace(w.Body.String()))
+	assert.Equal(t, `"1436773875771421417"`, w.Header().Get("ETag"))
+	assert.Equal(t, Hash(`{"uuid":"fake-data"}`), w.Header().Get(NativeHashHeader))
+}
+
+func TestReadContentWithStoredHash(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(
+			&mapper.Resource{
+				ContentType:     "application/json",
+				Content:         map[string]interface{}{"uuid": "fake-data"},
+				ContentRevision: 1436773875771421417,
+				ContentHash:     "stored-hash"},
+			true,
+			nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "stored-hash", w.Header().Get(NativeHashHeader))
 }
 
 func TestReadRevisions(t *testing.T) {
@@ -57,13 +86,35 @@ func TestReadRevisions(t *testing.T) {
 	assert.Equal(t, `[1,2,3]`, strings.TrimSpace(w.Body.String()))
 }
 
+func TestReadRevisionsWithoutDetails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisions", "universal-publishing", "a-real-uuid").
+		Return(
+			[]int64{1, 2, 3},
+			nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-publishing/a-real-uuid/revisions?details=false", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "ReadRevisionsPage", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[1,2,3]`, strings.TrimSpace(w.Body.String()))
+	assert.Empty(t, w.Header().Get("Link"))
+}
+
 func TestReadSingleRevision(t *testing.T) {
 	connection := new(MockConnection)
 	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1)).
 		Return(
 			&mapper.Resource{
-				ContentType: "application/json",
-				Content:     map[string]interface{}{"uuid": "fake-data"}},
+				ContentType:     "application/json",
+				Content:         map[string]interface{}{"uuid": "fake-data"},
+				ContentRevision: 1},
 			nil)
 
 	router := mux.NewRouter()
@@ -77,6 +128,7 @@ func TestReadSingleRevision(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
 	assert.Equal(t, `{"uuid":"fake-data"}`, strings.TrimSpace(w.Body.String()))
+	assert.Equal(t, `"1"`, w.Header().Get("ETag"))
 }
 
 func TestReadContentWithCharsetDirective(t *testing.T) {
@@ -128,7 +180,7 @@ func TestIDNotFound(t *testing.T) {
 
 func TestNoMapperImplemented(t *testing.T) {
 	connection := new(MockConnection)
-	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{ContentType: "application/vnd.fake-mime-type"}, true, nil)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{ContentType: "not a media type"}, true, nil)
 
 	router := mux.NewRouter()
 	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
@@ -175,7 +227,7 @@ func TestFailedMongoOnRead(t *testing.T) {
 func TestReadIDs(t *testing.T) {
 	connection := new(MockConnection)
 	ids := make(chan string, 1)
-	connection.On("ReadIDs", mock.AnythingOfType("*context.timerCtx"), "universal-content").Return(ids, nil)
+	connection.On("ReadIDs", mock.AnythingOfType("*context.timerCtx"), "universal-content", false).Return(ids, nil)
 
 	router := mux.NewRouter()
 	router.HandleFunc("/{collection}/__ids", ReadIDs(connection)).Methods("GET")
@@ -200,7 +252,7 @@ func TestReadIDsMongoCallFails(t *testing.T) {
 	ids := make(chan string, 1)
 
 	connection := new(MockConnection)
-	connection.On("ReadIDs", mock.AnythingOfType("*context.timerCtx"), "universal-content").Return(ids, errors.New(`oh no`))
+	connection.On("ReadIDs", mock.AnythingOfType("*context.timerCtx"), "universal-content", false).Return(ids, errors.New(`oh no`))
 
 	router := mux.NewRouter()
 	router.HandleFunc("/{collection}/__ids", ReadIDs(connection)).Methods("GET")
@@ -213,3 +265,371 @@ func TestReadIDsMongoCallFails(t *testing.T) {
 
 	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
 }
+
+func TestReadContentNotModified(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("If-None-Match", `"1436773875771421417"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Read", "universal-content", "a-real-uuid")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"1436773875771421417"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Mon, 13 Jul 2015 07:51:15 GMT", w.Header().Get("Last-Modified"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestReadContentRepublishedUnchangedNotModified(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(hashed(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{"title": "Title"}, ContentRevision: 1436773875771421417}), true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"`+titleHash+`"`, w.Header().Get("ETag"))
+
+	// the same content republished as a newer revision keeps its ETag
+	connection = new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", ContentRevision: 1436773875771421500, ContentHash: titleHash}, true, nil)
+
+	router = mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("If-None-Match", `"`+titleHash+`"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Read", "universal-content", "a-real-uuid")
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"`+titleHash+`"`, w.Header().Get("ETag"))
+}
+
+func TestReadContentModifiedSince(t *testing.T) {
+	connection := new(MockConnection)
+	resource := &mapper.Resource{
+		ContentType:     "application/json",
+		Content:         map[string]interface{}{"uuid": "fake-data"},
+		ContentRevision: 1436773875771421417,
+	}
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(resource, true, nil)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(resource, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("If-Modified-Since", "Mon, 13 Jul 2015 07:00:00 GMT")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Mon, 13 Jul 2015 07:51:15 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, `{"uuid":"fake-data"}`, strings.TrimSpace(w.Body.String()))
+}
+
+func TestReadSingleRevisionNotModified(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 1, Before: 1436773875771421418}).
+		Return([]*mapper.Resource{{ContentType: "application/json", ContentRevision: 1436773875771421417}}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}", ReadSingleRevision(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/1436773875771421417", http.NoBody)
+	req.Header.Set("If-Modified-Since", "Mon, 13 Jul 2015 08:00:00 GMT")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"1436773875771421417"`, w.Header().Get("ETag"))
+}
+
+func TestReadSingleRevisionNotModifiedButMissing(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 1, Before: 2}).
+		Return([]*mapper.Resource{}, false, nil)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1)).Return((*mapper.Resource)(nil), nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}", ReadSingleRevision(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/1", http.NoBody)
+	req.Header.Set("If-None-Match", `"1"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReadContentAsOf(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadAsOf", "universal-content", "a-real-uuid", int64(1436773875000000000)).
+		Return(
+			&mapper.Resource{
+				ContentType:     "application/json",
+				Content:         map[string]interface{}{"uuid": "fake-data"},
+				ContentRevision: 1436773874771421417},
+			true,
+			nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	for _, asOf := range []string{"2015-07-13T07:51:15Z", "2015-07-13T08:51:15%2B01:00", "1436773875000000000"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid?asOf="+asOf, http.NoBody)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, asOf)
+		assert.Equal(t, `{"uuid":"fake-data"}`, strings.TrimSpace(w.Body.String()))
+		assert.Equal(t, "1436773874771421417", w.Header().Get(ContentRevisionHeader))
+	}
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Read", "universal-content", "a-real-uuid")
+}
+
+func TestReadContentAsOfBeforeFirstRevision(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadAsOf", "universal-content", "a-real-uuid", int64(1)).
+		Return((*mapper.Resource)(nil), false, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid?asOf=1", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReadContentInvalidAsOf(t *testing.T) {
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid?asOf=yesterday", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReadIDsIncludingDeleted(t *testing.T) {
+	connection := new(MockConnection)
+	ids := make(chan string, 1)
+	connection.On("ReadIDs", mock.AnythingOfType("*context.timerCtx"), "universal-content", true).Return(ids, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/__ids", ReadIDs(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/__ids?includeDeleted=true", http.NoBody)
+
+	ids <- "hi"
+	close(ids)
+
+	router.ServeHTTP(w, req)
+
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"id":"hi"}`, strings.TrimSpace(w.Body.String()))
+}
+
+func TestReadBinaryContent(t *testing.T) {
+	image := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0xff}
+
+	connection := new(MockConnection)
+	connection.On("Read", "images", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "image/png", Content: image, ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/images/a-real-uuid", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	assert.Equal(t, image, w.Body.Bytes())
+	assert.Equal(t, Hash(string(image)), w.Header().Get(NativeHashHeader))
+}
+
+func TestReadContentAsYAML(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{"uuid": "fake-data"}, ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("Accept", "application/yaml")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/yaml", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	assert.Equal(t, "uuid: fake-data\n", w.Body.String())
+	assert.Equal(t, Hash(`{"uuid":"fake-data"}`), w.Header().Get(NativeHashHeader), "the hash is the one of the stored content")
+	assert.Equal(t, `"1436773875771421417+yaml"`, w.Header().Get("ETag"))
+}
+
+func TestReadContentAsYAMLNotModified(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("Accept", "application/yaml")
+	req.Header.Set("If-None-Match", `"1436773875771421417+yaml"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, `"1436773875771421417+yaml"`, w.Header().Get("ETag"))
+}
+
+func TestReadContentInOtherRepresentationIsModified(t *testing.T) {
+	connection := new(MockConnection)
+	resource := &mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{"uuid": "fake-data"}, ContentRevision: 1436773875771421417}
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(resource, true, nil)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(resource, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("Accept", "application/cbor")
+	req.Header.Set("If-None-Match", `"1436773875771421417"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/cbor", w.Header().Get("Content-Type"))
+	assert.Equal(t, `"1436773875771421417+cbor"`, w.Header().Get("ETag"))
+}
+
+func TestReadJSONVariantAcceptedAsJSON(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/vnd.ft-upp-article+json", Content: map[string]interface{}{"uuid": "fake-data"}, ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("Accept", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.ft-upp-article+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `"1436773875771421417"`, w.Header().Get("ETag"))
+}
+
+func TestReadContentPrefersStoredContentType(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/vnd.ft-upp-article+json", Content: map[string]interface{}{"uuid": "fake-data"}, ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("Accept", "application/cbor;q=0.5, application/*")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/vnd.ft-upp-article+json", w.Header().Get("Content-Type"))
+	assert.Equal(t, `{"uuid":"fake-data"}`, strings.TrimSpace(w.Body.String()))
+}
+
+func TestReadContentNotAcceptable(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{"uuid": "fake-data"}, ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid", http.NoBody)
+	req.Header.Set("Accept", "text/html")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestReadBinaryContentNotAcceptable(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "images", "a-real-uuid").
+		Return(&mapper.Resource{ContentType: "image/png", Content: []byte{0x89, 'P', 'N', 'G'}, ContentRevision: 1436773875771421417}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", ReadContent(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/images/a-real-uuid", http.NoBody)
+	req.Header.Set("Accept", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
+
+func TestReadSingleRevisionAsMessagePack(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(&mapper.Resource{ContentType: "application/json", Content: map[string]interface{}{"a": json.Number("1")}, ContentRevision: 1436773875771421417}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}", ReadSingleRevision(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/1436773875771421417", http.NoBody)
+	req.Header.Set("Accept", "application/msgpack")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+	assert.Equal(t, []byte{0x81, 0xa1, 'a', 0x01}, w.Body.Bytes())
+	assert.Equal(t, `"1436773875771421417+msgpack"`, w.Header().Get("ETag"))
+}
@@ -0,0 +1,168 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// RestoreContent writes a historical revision of a native record as its new latest revision
+func RestoreContent(connection db.Connection, ts TimestampCreator) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		collectionID := mux.Vars(r)["collection"]
+		resourceID := mux.Vars(r)["resource"]
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+
+		revision, err := strconv.ParseInt(mux.Vars(r)["revision"], 10, 64)
+		if err != nil {
+			msg := "Invalid content-revision"
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		original, err := connection.ReadSingleRevision(collectionID, resourceID, revision)
+		if err != nil {
+			msg := "Reading from mongoDB failed."
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if original == nil {
+			msg := fmt.Sprintf("Resource not found, collection=%v, id=%v, revision=%v", collectionID, resourceID, revision)
+			logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+			writeMessage(w, msg, http.StatusNotFound)
+			return
+		}
+
+		if original.Deleted {
+			msg := fmt.Sprintf("Revision records a deletion and cannot be restored, collection=%v, id=%v, revision=%v", collectionID, resourceID, revision)
+			logger.WithTransactionID(tid).WithUUID(resourceID).Warn(msg)
+			writeMessage(w, msg, http.StatusConflict)
+			return
+		}
+
+		restoreRevision(w, connection, "RestoreToNative", collectionID, original, ts.CreateTimestamp(), preconditionFromRequest(r), http.StatusPreconditionFailed, tid)
+	}
+}
+
+// UndeleteContent brings back a deleted native record, by writing its last revision before the deletion as a new revision
+func UndeleteContent(connection db.Connection, ts TimestampCreator) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		collectionID := mux.Vars(r)["collection"]
+		resourceID := mux.Vars(r)["resource"]
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+
+		latest, found, err := connection.ReadMetadata(collectionID, resourceID)
+		if err != nil {
+			msg := "Reading from mongoDB failed."
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			msg := fmt.Sprintf("Resource not found, collection=%v, id=%v", collectionID, resourceID)
+			logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+			writeMessage(w, msg, http.StatusNotFound)
+			return
+		}
+		if !latest.Deleted {
+			msg := fmt.Sprintf("Resource is not deleted, collection=%v, id=%v", collectionID, resourceID)
+			logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+			writeMessage(w, msg, http.StatusConflict)
+			return
+		}
+
+		original, err := lastRevisionBeforeDeletion(r.Context(), connection, collectionID, resourceID)
+		if err != nil {
+			msg := "Reading from mongoDB failed."
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if original == nil {
+			msg := fmt.Sprintf("No revision to restore, collection=%v, id=%v", collectionID, resourceID)
+			logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+			writeMessage(w, msg, http.StatusNotFound)
+			return
+		}
+
+		// without an explicit precondition, the document must not have changed since the deletion was found
+		precondition := preconditionFromRequest(r)
+		preconditionFailedStatus := http.StatusPreconditionFailed
+		if precondition == nil {
+			ifLatest := db.IfLatest(latest.ContentRevision)
+			precondition = &ifLatest
+			preconditionFailedStatus = http.StatusConflict
+		}
+
+		restoreRevision(w, connection, "UndeleteToNative", collectionID, original, ts.CreateTimestamp(), precondition, preconditionFailedStatus, tid)
+	}
+}
+
+// lastRevisionBeforeDeletion finds the newest revision of a document which is not a deletion, or nil if there is none.
+// The revisions are listed without their content, so that only the revision found is read in full.
+func lastRevisionBeforeDeletion(ctx context.Context, connection db.Connection, collectionID, resourceID string) (*mapper.Resource, error) {
+	revisions, err := connection.ReadRevisionsMetadata(ctx, collectionID, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(revisions) - 1; i >= 0; i-- {
+		if !revisions[i].Deleted {
+			return connection.ReadSingleRevision(collectionID, resourceID, revisions[i].ContentRevision)
+		}
+	}
+	return nil, nil
+}
+
+// restoreRevision writes the content of an earlier revision as a new revision, keeping its metadata,
+// and logs the outcome under the given monitoring event
+func restoreRevision(w http.ResponseWriter, connection db.Connection, event string, collectionID string, original *mapper.Resource, contentRevision int64, precondition *db.Precondition, preconditionFailedStatus int, tid string) {
+	restored := mapper.Wrap(original.Content, original.UUID, original.ContentType, original.OriginSystemID, original.SchemaVersion, contentRevision)
+
+	err := writeResource(connection, collectionID, restored, precondition)
+	if errors.Is(err, db.ErrPreconditionFailed) {
+		msg := "Precondition failed"
+		logger.WithMonitoringEvent(event, tid, original.ContentType).WithUUID(original.UUID).WithError(err).Warn(msg)
+		http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), preconditionFailedStatus)
+		return
+	}
+	if errors.Is(err, db.ErrContentTooLarge) {
+		msg := "Content too large"
+		logger.WithMonitoringEvent(event, tid, original.ContentType).WithUUID(original.UUID).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		msg := "Writing to mongoDB failed"
+		logger.WithMonitoringEvent(event, tid, original.ContentType).WithUUID(original.UUID).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set(ContentRevisionHeader, strconv.FormatInt(contentRevision, 10))
+	setRevisionHeaders(w, restored)
+
+	logger.WithMonitoringEvent(event, tid, original.ContentType).
+		WithUUID(original.UUID).
+		WithField("collection", collectionID).
+		WithField("origin-system-id", original.OriginSystemID).
+		WithField("schema-version", original.SchemaVersion).
+		WithField("restored-revision", original.ContentRevision).
+		WithField("content-revision", contentRevision).
+		Info("Successfully restored")
+}
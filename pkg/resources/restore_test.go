@@ -0,0 +1,322 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/Financial-Times/nativerw/pkg/config"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/maintenance"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func TestRestoreContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1)).
+		Return(
+			&mapper.Resource{
+				UUID:            "a-real-uuid",
+				Content:         map[string]interface{}{"title": "Title"},
+				ContentType:     "application/vnd.ft-upp-article+json",
+				OriginSystemID:  "methode-web-pub",
+				SchemaVersion:   "3",
+				ContentRevision: 1},
+			nil)
+	connection.On("Write",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{"title": "Title"},
+			ContentType:     "application/vnd.ft-upp-article+json",
+			OriginSystemID:  "methode-web-pub",
+			SchemaVersion:   "3",
+			ContentRevision: 1436773875771421417})).
+		Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}/restore", RestoreContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/1/restore", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1436773875771421417", w.Header().Get(ContentRevisionHeader))
+	assert.Equal(t, `"`+titleHash+`"`, w.Header().Get("ETag"))
+}
+
+func TestRestoreContentIfMatch(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1)).
+		Return(&mapper.Resource{UUID: "a-real-uuid", Content: map[string]interface{}{}, ContentType: "application/json", ContentRevision: 1}, nil)
+	connection.On("WriteConditionally",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{},
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417}),
+		db.IfLatest(2)).
+		Return(db.ErrPreconditionFailed)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}/restore", RestoreContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/1/restore", http.NoBody)
+	req.Header.Add("If-Match", `"2"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestRestoreContentRevisionNotFound(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1)).
+		Return((*mapper.Resource)(nil), nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}/restore", RestoreContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/1/restore", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestRestoreContentReadFails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1)).
+		Return((*mapper.Resource)(nil), errors.New("i failed"))
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}/restore", RestoreContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/1/restore", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRestoreContentInvalidRevision(t *testing.T) {
+	connection := new(MockConnection)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}/restore", RestoreContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/latest/restore", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestRestoreContentDeletion(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(2)).
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 2, Deleted: true}, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/{revision}/restore", RestoreContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/2/restore", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestUndeleteContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 4, Deleted: true}, true, nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").
+		Return([]*mapper.Resource{
+			{UUID: "a-real-uuid", ContentRevision: 1},
+			{UUID: "a-real-uuid", ContentRevision: 2},
+			{UUID: "a-real-uuid", ContentRevision: 3, Deleted: true},
+			{UUID: "a-real-uuid", ContentRevision: 4, Deleted: true},
+		}, nil)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(2)).
+		Return(
+			&mapper.Resource{
+				UUID:            "a-real-uuid",
+				Content:         map[string]interface{}{"title": "Title"},
+				ContentType:     "application/json",
+				OriginSystemID:  "methode-web-pub",
+				SchemaVersion:   "3",
+				ContentRevision: 2},
+			nil)
+	connection.On("WriteConditionally",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{"title": "Title"},
+			ContentType:     "application/json",
+			OriginSystemID:  "methode-web-pub",
+			SchemaVersion:   "3",
+			ContentRevision: 1436773875771421417}),
+		db.IfLatest(4)).
+		Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/undelete", UndeleteContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/undelete", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNumberOfCalls(t, "ReadSingleRevision", 1)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1436773875771421417", w.Header().Get(ContentRevisionHeader))
+}
+
+func TestUndeleteContentNeverExisted(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return((*mapper.Resource)(nil), false, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/undelete", UndeleteContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/undelete", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestUndeleteContentNotDeleted(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 4}, true, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/undelete", UndeleteContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/undelete", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestUndeleteContentChangedConcurrently(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(&mapper.Resource{UUID: "a-real-uuid", ContentType: "application/json", ContentRevision: 2, Deleted: true}, true, nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").
+		Return([]*mapper.Resource{
+			{UUID: "a-real-uuid", ContentRevision: 1},
+			{UUID: "a-real-uuid", ContentRevision: 2, Deleted: true},
+		}, nil)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1)).
+		Return(&mapper.Resource{UUID: "a-real-uuid", Content: map[string]interface{}{}, ContentType: "application/json", ContentRevision: 1}, nil)
+	connection.On("WriteConditionally", "universal-content", mock.AnythingOfType("*mapper.Resource"), db.IfLatest(2)).
+		Return(db.ErrPreconditionFailed)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/undelete", UndeleteContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/undelete", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestUndeleteContentAfterRetention(t *testing.T) {
+	revisions := []*mapper.Resource{
+		{UUID: "a-real-uuid", ContentRevision: 1},
+		{UUID: "a-real-uuid", ContentRevision: 2},
+		{UUID: "a-real-uuid", ContentRevision: 3, Deleted: true},
+	}
+
+	documents := make(chan string, 1)
+	documents <- "a-real-uuid"
+	close(documents)
+
+	connection := new(MockConnection)
+	connection.On("ReadIDs", mock.Anything, "universal-content", true).Return(documents, nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").Return(revisions, nil)
+	var expired []int64
+	connection.On("DeleteRevisions", mock.Anything, "universal-content", "a-real-uuid", mock.Anything).
+		Run(func(args mock.Arguments) { expired = args.Get(3).([]int64) }).
+		Return(int64(1), nil)
+
+	job := maintenance.NewRetentionJob(connection, map[string]config.Retention{"universal-content": {KeepLast: 1}}, time.Hour, false)
+	assert.NoError(t, job.Run(context.Background()))
+	assert.Equal(t, []int64{1}, expired)
+
+	var kept []*mapper.Resource
+	for _, revision := range revisions {
+		if revision.ContentRevision != expired[0] {
+			kept = append(kept, revision)
+		}
+	}
+	connection = new(MockConnection)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").Return(revisions[2], true, nil)
+	connection.On("ReadRevisionsMetadata", mock.Anything, "universal-content", "a-real-uuid").Return(kept, nil)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(2)).
+		Return(&mapper.Resource{UUID: "a-real-uuid", Content: map[string]interface{}{"title": "Title"}, ContentType: "application/json", ContentRevision: 2}, nil)
+	connection.On("WriteConditionally",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{"title": "Title"},
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417}),
+		db.IfLatest(3)).
+		Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/undelete", UndeleteContent(connection, &ts)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid/undelete", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,203 @@
+package resources
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+const (
+	defaultRevisionsLimit = 100
+	maxRevisionsLimit     = 1000
+)
+
+// revisionDetails describes a single revision in the detailed revisions listing.
+// Size and Hash are those of the JSON serialisation of the content, or its raw bytes, stored with the revision for the X-Native-Hash check.
+type revisionDetails struct {
+	Revision       int64  `json:"revision"`
+	Timestamp      string `json:"timestamp"`
+	OriginSystemID string `json:"origin-system-id"`
+	SchemaVersion  string `json:"schema-version"`
+	ContentType    string `json:"content-type"`
+	Size           int64  `json:"size"`
+	Hash           string `json:"hash"`
+	Deleted        bool   `json:"deleted,omitempty"`
+}
+
+// isRevisionsPageRequest tells whether details or pagination are requested, in which case the revisions are listed a page at a time
+func isRevisionsPageRequest(query url.Values) bool {
+	if details, _ := strconv.ParseBool(query.Get("details")); details {
+		return true
+	}
+	for _, param := range []string{"limit", "before", "after"} {
+		if query.Get(param) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRevisionPage(query url.Values) (db.RevisionPage, error) {
+	page := db.RevisionPage{Limit: defaultRevisionsLimit}
+
+	if limit := query.Get("limit"); limit != "" {
+		l, err := strconv.ParseInt(limit, 10, 64)
+		if err != nil {
+			return page, err
+		}
+		if l < 1 || l > maxRevisionsLimit {
+			return page, fmt.Errorf("limit must be between 1 and %d", maxRevisionsLimit)
+		}
+		page.Limit = l
+	}
+
+	var err error
+	if page.Before, err = parseOptionalRevision(query.Get("before")); err != nil {
+		return page, err
+	}
+	if page.After, err = parseOptionalRevision(query.Get("after")); err != nil {
+		return page, err
+	}
+	if page.Before != 0 && page.After != 0 {
+		return page, errors.New("only one of before and after can be used")
+	}
+
+	return page, nil
+}
+
+// readRevisionsPage writes a page of revisions newest first, either as bare revisions or with their details,
+// and links to the neighbouring pages in the Link header
+func readRevisionsPage(w http.ResponseWriter, r *http.Request, connection db.Connection, collection, resourceID, tid string) {
+	query := r.URL.Query()
+	page, err := parseRevisionPage(query)
+	if err != nil {
+		msg := "Invalid revisions page"
+		logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+		return
+	}
+
+	resources, more, err := connection.ReadRevisionsPage(collection, resourceID, page)
+	if err != nil {
+		msg := "Reading from mongoDB failed."
+		logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if len(resources) == 0 && page.Before == 0 && page.After == 0 {
+		msg := fmt.Sprintf("Resource not found, collection=%v, id=%v", collection, resourceID)
+		logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+		writeMessage(w, msg, http.StatusNotFound)
+		return
+	}
+
+	var body interface{}
+	if details, _ := strconv.ParseBool(query.Get("details")); details {
+		body, err = describeRevisions(connection, collection, resources)
+	} else {
+		revisions := make([]int64, len(resources))
+		for i, resource := range resources {
+			revisions[i] = resource.ContentRevision
+		}
+		body = revisions
+	}
+	if err != nil {
+		msg := "Unable to describe revisions."
+		logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	respBody, err := json.Marshal(body)
+	if err != nil {
+		msg := "Unable to serialize revisions."
+		logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+		http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	if links := revisionsPageLinks(r.URL, page, resources, more); links != "" {
+		w.Header().Set("Link", links)
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, string(respBody))
+}
+
+// describeRevisions details revisions read without their content from their stored hash and size.
+// Revisions written before these were stored, which have not been backfilled, are read in full to compute them.
+func describeRevisions(connection db.Connection, collection string, resources []*mapper.Resource) ([]revisionDetails, error) {
+	details := make([]revisionDetails, len(resources))
+	for i, resource := range resources {
+		if !resource.Deleted && (resource.ContentHash == "" || resource.ContentSize == 0) {
+			stored, err := connection.ReadSingleRevision(collection, resource.UUID, resource.ContentRevision)
+			if err != nil {
+				return nil, err
+			}
+			if stored != nil {
+				if err = HashResource(stored); err != nil {
+					return nil, err
+				}
+				resource = stored
+			}
+		}
+
+		details[i] = revisionDetails{
+			Revision:       resource.ContentRevision,
+			Timestamp:      lastModified(resource.ContentRevision).Format(time.RFC3339Nano),
+			OriginSystemID: resource.OriginSystemID,
+			SchemaVersion:  resource.SchemaVersion,
+			ContentType:    resource.ContentType,
+			Size:           resource.ContentSize,
+			Hash:           resource.ContentHash,
+			Deleted:        resource.Deleted,
+		}
+	}
+	return details, nil
+}
+
+// revisionsPageLinks links to the older revisions with rel="next" and to the newer ones with rel="prev"
+func revisionsPageLinks(u *url.URL, page db.RevisionPage, resources []*mapper.Resource, more bool) string {
+	if len(resources) == 0 {
+		return ""
+	}
+
+	newest := resources[0].ContentRevision
+	oldest := resources[len(resources)-1].ContentRevision
+
+	hasOlder := more
+	hasNewer := page.Before != 0
+	if page.After != 0 {
+		hasOlder, hasNewer = true, more
+	}
+
+	var links []string
+	if hasOlder {
+		links = append(links, revisionsPageLink(u, page.Limit, "before", oldest, "next"))
+	}
+	if hasNewer {
+		links = append(links, revisionsPageLink(u, page.Limit, "after", newest, "prev"))
+	}
+	return strings.Join(links, ", ")
+}
+
+func revisionsPageLink(u *url.URL, limit int64, cursor string, revision int64, rel string) string {
+	query := u.Query()
+	query.Del("before")
+	query.Del("after")
+	query.Set("limit", strconv.FormatInt(limit, 10))
+	query.Set(cursor, strconv.FormatInt(revision, 10))
+
+	link := url.URL{Path: u.Path, RawQuery: query.Encode()}
+	return fmt.Sprintf(`<%s>; rel="%s"`, link.String(), rel)
+}
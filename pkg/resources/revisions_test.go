@@ -0,0 +1,179 @@
+package resources
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func revisionsPage(revisions ...int64) []*mapper.Resource {
+	resources := make([]*mapper.Resource, len(revisions))
+	for i, revision := range revisions {
+		resources[i] = &mapper.Resource{
+			UUID:            "a-real-uuid",
+			ContentType:     "application/json",
+			OriginSystemID:  "methode-web-pub",
+			SchemaVersion:   "3",
+			ContentRevision: revision,
+			ContentHash:     Hash(`{"title":"Title"}`),
+			ContentSize:     17,
+		}
+	}
+	return resources
+}
+
+func TestReadRevisionsWithDetails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 100}).
+		Return(revisionsPage(1436773875771421417), false, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/revisions?details=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.Empty(t, w.Header().Get("Link"))
+	assert.JSONEq(t, `[{
+		"revision": 1436773875771421417,
+		"timestamp": "2015-07-13T07:51:15.771421417Z",
+		"origin-system-id": "methode-web-pub",
+		"schema-version": "3",
+		"content-type": "application/json",
+		"size": 17,
+		"hash": "`+Hash(`{"title":"Title"}`)+`"
+	}]`, w.Body.String())
+}
+
+func TestReadRevisionsWithDetailsOfUnhashedRevision(t *testing.T) {
+	unhashed := revisionsPage(1436773875771421417)
+	unhashed[0].ContentHash = ""
+	unhashed[0].ContentSize = 0
+
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 100}).
+		Return(unhashed, false, nil)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{"title": "Title"},
+			ContentType:     "application/json",
+			OriginSystemID:  "methode-web-pub",
+			SchemaVersion:   "3",
+			ContentRevision: 1436773875771421417,
+		}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/revisions?details=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{
+		"revision": 1436773875771421417,
+		"timestamp": "2015-07-13T07:51:15.771421417Z",
+		"origin-system-id": "methode-web-pub",
+		"schema-version": "3",
+		"content-type": "application/json",
+		"size": 17,
+		"hash": "`+Hash(`{"title":"Title"}`)+`"
+	}]`, w.Body.String())
+}
+
+func TestReadRevisionsFirstPage(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 2}).
+		Return(revisionsPage(5, 4), true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/revisions?limit=2", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[5,4]`, w.Body.String())
+	assert.Equal(t, `</universal-content/a-real-uuid/revisions?before=4&limit=2>; rel="next"`, w.Header().Get("Link"))
+}
+
+func TestReadRevisionsPageBefore(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 2, Before: 4}).
+		Return(revisionsPage(3, 2), true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/revisions?limit=2&before=4&details=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `</universal-content/a-real-uuid/revisions?before=2&details=true&limit=2>; rel="next", `+
+		`</universal-content/a-real-uuid/revisions?after=3&details=true&limit=2>; rel="prev"`, w.Header().Get("Link"))
+}
+
+func TestReadRevisionsPageAfter(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 2, After: 3}).
+		Return(revisionsPage(5, 4), false, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/revisions?limit=2&after=3", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `[5,4]`, w.Body.String())
+	assert.Equal(t, `</universal-content/a-real-uuid/revisions?before=4&limit=2>; rel="next"`, w.Header().Get("Link"))
+}
+
+func TestReadRevisionsPageNotFound(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadRevisionsPage", "universal-content", "a-real-uuid", db.RevisionPage{Limit: 100}).
+		Return([]*mapper.Resource{}, false, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/revisions?details=true", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestReadRevisionsInvalidPage(t *testing.T) {
+	for _, query := range []string{"limit=0", "limit=1001", "limit=ten", "before=abc", "before=2&after=1"} {
+		connection := new(MockConnection)
+
+		router := mux.NewRouter()
+		router.HandleFunc("/{collection}/{resource}/revisions", ReadRevisions(connection)).Methods("GET")
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/revisions?"+query, http.NoBody)
+
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
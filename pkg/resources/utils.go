@@ -2,6 +2,7 @@ package resources
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -31,3 +32,12 @@ func extractAttrFromHeader(r *http.Request, attrName, defValue, tid, resourceID
 
 	return val
 }
+
+// bodyErrorStatus answers a body which could not be read with 413 if it was cut by LimitBodySize, and 400 otherwise
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
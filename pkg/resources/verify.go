@@ -0,0 +1,88 @@
+package resources
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/nativerw/pkg/db"
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// hashVerification compares the hash stored with a revision to the hash of its content; stored is empty for revisions written before hashes were stored
+type hashVerification struct {
+	Stored   string `json:"stored,omitempty"`
+	Computed string `json:"computed"`
+	Matches  bool   `json:"matches"`
+}
+
+type hashReport struct {
+	Revision int64                       `json:"revision"`
+	Hashes   map[string]hashVerification `json:"hashes"`
+}
+
+// VerifyHash reports the hashes of the latest revision of a native record, or of the revision given in ?revision=, under each algorithm
+func VerifyHash(connection db.Connection) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		tid := transactionidutils.GetTransactionIDFromRequest(r)
+		collectionID := mux.Vars(r)["collection"]
+		resourceID := mux.Vars(r)["resource"]
+
+		revision, err := parseOptionalRevision(r.URL.Query().Get("revision"))
+		if err != nil {
+			msg := "Invalid content-revision"
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			return
+		}
+
+		var resource *mapper.Resource
+		found := true
+		if revision == 0 {
+			resource, found, err = connection.Read(collectionID, resourceID)
+		} else {
+			resource, err = connection.ReadSingleRevision(collectionID, resourceID, revision)
+		}
+		if err != nil {
+			msg := "Reading from mongoDB failed."
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf(msg+": %v", err.Error()), http.StatusInternalServerError)
+			return
+		}
+		if !found || resource == nil {
+			msg := fmt.Sprintf("Resource not found, collection=%v, id=%v", collectionID, resourceID)
+			logger.WithTransactionID(tid).WithUUID(resourceID).Info(msg)
+			writeMessage(w, msg, http.StatusNotFound)
+			return
+		}
+		if resource.Deleted {
+			writeGone(w, resource, tid)
+			return
+		}
+
+		computed := &mapper.Resource{Content: resource.Content}
+		if err := HashResource(computed); err != nil {
+			msg := "Hashing the content failed"
+			logger.WithTransactionID(tid).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusInternalServerError)
+			return
+		}
+
+		report := hashReport{Revision: resource.ContentRevision, Hashes: map[string]hashVerification{}}
+		for _, algorithm := range []string{LegacyHashAlgorithm, CanonicalHashAlgorithm} {
+			verification := hashVerification{Stored: storedHash(resource, algorithm), Computed: storedHash(computed, algorithm)}
+			verification.Matches = verification.Stored == verification.Computed
+			report.Hashes[algorithm] = verification
+		}
+
+		respBody, _ := json.Marshal(report)
+		w.Header().Add("Content-Type", "application/json")
+		fmt.Fprint(w, string(respBody))
+	}
+}
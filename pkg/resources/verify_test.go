@@ -0,0 +1,105 @@
+package resources
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Financial-Times/nativerw/pkg/mapper"
+)
+
+func TestVerifyHashOfLatestRevision(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{
+		UUID:            "a-real-uuid",
+		Content:         map[string]interface{}{"b": 1.0, "a": "x"},
+		ContentType:     "application/json",
+		ContentRevision: 3,
+		ContentHash:     "ff1aae8b58f13647944546c6bb2e037bfd3b286a68378acb66d21a4b",
+		CanonicalHash:   "a-corrupted-hash",
+	}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/hash", VerifyHash(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/hash", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"revision":3,"hashes":{
+		"sha224":{"stored":"ff1aae8b58f13647944546c6bb2e037bfd3b286a68378acb66d21a4b","computed":"ff1aae8b58f13647944546c6bb2e037bfd3b286a68378acb66d21a4b","matches":true},
+		"sha256-jcs":{"stored":"a-corrupted-hash","computed":"cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246","matches":false}
+	}}`, w.Body.String())
+}
+
+func TestVerifyHashOfRevisionWithoutStoredHash(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("ReadSingleRevision", "universal-content", "a-real-uuid", int64(2)).Return(&mapper.Resource{
+		UUID:            "a-real-uuid",
+		Content:         map[string]interface{}{"a": "x", "b": 1.0},
+		ContentType:     "application/json",
+		ContentRevision: 2,
+	}, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/hash", VerifyHash(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/hash?revision=2", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"revision":2,"hashes":{
+		"sha224":{"computed":"ff1aae8b58f13647944546c6bb2e037bfd3b286a68378acb66d21a4b","matches":false},
+		"sha256-jcs":{"computed":"cdab067e9f3beb32d1252cfd63e492592fecbf591b0d08cadb24bb17f3864246","matches":false}
+	}}`, w.Body.String())
+}
+
+func TestVerifyHashNotFound(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").Return((*mapper.Resource)(nil), false, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/hash", VerifyHash(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/hash", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestVerifyHashOfDeletedDocument(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Read", "universal-content", "a-real-uuid").Return(&mapper.Resource{UUID: "a-real-uuid", ContentRevision: 4, Deleted: true}, true, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/hash", VerifyHash(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/hash", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Equal(t, "4", w.Header().Get(ContentRevisionHeader))
+}
+
+func TestVerifyHashInvalidRevision(t *testing.T) {
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}/hash", VerifyHash(connection)).Methods("GET")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/universal-content/a-real-uuid/hash?revision=latest", http.NoBody)
+
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
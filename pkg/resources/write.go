@@ -1,6 +1,7 @@
 package resources
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -13,8 +14,9 @@ import (
 	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
 )
 
-// WriteContent writes a new native record
-func WriteContent(connection db.Connection, ts TimestampCreator) func(w http.ResponseWriter, r *http.Request) {
+// WriteContent writes a new native record.
+// In the collections set in skipUnchanged, a record identical to the latest revision is not written again.
+func WriteContent(connection db.Connection, ts TimestampCreator, skipUnchanged map[string]bool) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 
@@ -51,7 +53,7 @@ func WriteContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 		if err != nil {
 			msg := "Extracting content from HTTP body failed"
 			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Error(msg)
-			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), bodyErrorStatus(err))
 			return
 		}
 
@@ -59,7 +61,8 @@ func WriteContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 		if err != nil {
 			msg := "Failed to check if content-revision exists!"
 			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Error(msg)
-			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusServiceUnavailable)
+			return
 		}
 		if cnt > 0 {
 			logger.WithMonitoringEvent("SaveToNative", tid, contentType).
@@ -74,14 +77,55 @@ func WriteContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 		}
 
 		wrappedContent := mapper.Wrap(content, resourceID, contentType, originSystemIDHeader, schemaVersion, contentRevision)
+		precondition := preconditionFromRequest(r)
+
+		// a precondition must still be evaluated against the latest revision, so the write goes ahead
+		if skipUnchanged[collectionID] && precondition == nil {
+			latest, unchanged, err := unchangedRevision(connection, collectionID, wrappedContent)
+			if err != nil {
+				msg := "Failed to check if the content has changed"
+				logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Error(msg)
+				http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusInternalServerError)
+				return
+			}
+			if unchanged {
+				w.Header().Set(ContentRevisionHeader, strconv.FormatInt(latest.ContentRevision, 10))
+				setRevisionHeaders(w, latest)
 
-		if err := connection.Write(collectionID, wrappedContent); err != nil {
+				logger.WithMonitoringEvent("SaveToNative", tid, contentType).
+					WithUUID(resourceID).
+					WithField("collection", collectionID).
+					WithField("origin-system-id", originSystemIDHeader).
+					WithField("schema-version", schemaVersion).
+					WithField("content-revision", latest.ContentRevision).
+					Info("Content is unchanged. Skipping save")
+				writeMessage(w, "Content is unchanged, no need to write a new revision.", http.StatusOK)
+				return
+			}
+		}
+
+		err = writeResource(connection, collectionID, wrappedContent, precondition)
+		if errors.Is(err, db.ErrPreconditionFailed) {
+			msg := "Precondition failed"
+			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Warn(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, db.ErrContentTooLarge) {
+			msg := "Content too large"
+			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Error(msg)
+			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
 			msg := "Writing to mongoDB failed"
 			logger.WithMonitoringEvent("SaveToNative", tid, contentType).WithUUID(resourceID).WithError(err).Error(msg)
 			http.Error(w, fmt.Sprintf("%s\n%v\n", msg, err), http.StatusInternalServerError)
 			return
 		}
 
+		setRevisionHeaders(w, wrappedContent)
+
 		logger.WithMonitoringEvent("SaveToNative", tid, contentType).
 			WithUUID(resourceID).
 			WithField("collection", collectionID).
@@ -91,3 +135,27 @@ func WriteContent(connection db.Connection, ts TimestampCreator) func(w http.Res
 			Info("Successfully saved")
 	}
 }
+
+// unchangedRevision compares a record with the latest revision of the document, and returns the latest revision if they are identical.
+// The contents are compared by their canonical hash, so key order and number formatting do not matter, along with the content type, origin system id and schema version.
+func unchangedRevision(connection db.Connection, collectionID string, resource *mapper.Resource) (*mapper.Resource, bool, error) {
+	latest, found, err := readLatestHash(connection, collectionID, resource.UUID)
+	if err != nil || !found || latest.Deleted {
+		return nil, false, err
+	}
+
+	if latest.ContentType != resource.ContentType ||
+		latest.OriginSystemID != resource.OriginSystemID ||
+		latest.SchemaVersion != resource.SchemaVersion {
+		return nil, false, nil
+	}
+
+	if err := HashResource(resource); err != nil {
+		return nil, false, err
+	}
+	// content without a canonical hash can only be compared as it is written
+	if resource.CanonicalHash == "" {
+		return latest, latest.ContentHash == resource.ContentHash, nil
+	}
+	return latest, latest.CanonicalHash == resource.CanonicalHash, nil
+}
@@ -1,7 +1,10 @@
 package resources
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -9,7 +12,9 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
+	"github.com/Financial-Times/nativerw/pkg/db"
 	"github.com/Financial-Times/nativerw/pkg/mapper"
 )
 
@@ -23,11 +28,11 @@ func TestWriteContent(t *testing.T) {
 	connection := new(MockConnection)
 	connection.On("Write",
 		"universal-content",
-		&mapper.Resource{
+		hashed(&mapper.Resource{
 			UUID:            "a-real-uuid",
 			Content:         map[string]interface{}{},
 			ContentType:     "application/json",
-			ContentRevision: 1436773875771421417}).
+			ContentRevision: 1436773875771421417})).
 		Return(nil)
 	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
 		Return(0, nil)
@@ -35,7 +40,7 @@ func TestWriteContent(t *testing.T) {
 	ts := fixedTimestampCreator{}
 
 	router := mux.NewRouter()
-	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts)).Methods("POST")
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
@@ -55,7 +60,7 @@ func TestWriteContentWhenContentRevisionExists(t *testing.T) {
 	ts := fixedTimestampCreator{}
 
 	router := mux.NewRouter()
-	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts)).Methods("POST")
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
@@ -67,16 +72,37 @@ func TestWriteContentWhenContentRevisionExists(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 }
 
+func TestWriteContentWhenCountFails(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, errors.New("no reachable servers"))
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
+
+	req.Header.Add("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
 func TestWriteContentWithCharsetDirective(t *testing.T) {
 	connection := new(MockConnection)
 
 	connection.On("Write",
 		"universal-content",
-		&mapper.Resource{
+		hashed(&mapper.Resource{
 			UUID:            "a-real-uuid",
 			Content:         map[string]interface{}{},
 			ContentType:     "application/json; charset=utf-8",
-			ContentRevision: 1436773875771421417}).
+			ContentRevision: 1436773875771421417})).
 		Return(nil)
 	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
 		Return(0, nil)
@@ -84,7 +110,7 @@ func TestWriteContentWithCharsetDirective(t *testing.T) {
 	ts := fixedTimestampCreator{}
 
 	router := mux.NewRouter()
-	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts)).Methods("POST")
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
@@ -100,11 +126,11 @@ func TestWriteFailed(t *testing.T) {
 	connection := new(MockConnection)
 	connection.On("Write",
 		"universal-content",
-		&mapper.Resource{
+		hashed(&mapper.Resource{
 			UUID:            "a-real-uuid",
 			Content:         map[string]interface{}{},
 			ContentType:     "application/json",
-			ContentRevision: 1436773875771421417}).
+			ContentRevision: 1436773875771421417})).
 		Return(errors.New("i failed"))
 
 	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
@@ -113,7 +139,7 @@ func TestWriteFailed(t *testing.T) {
 	ts := fixedTimestampCreator{}
 
 	router := mux.NewRouter()
-	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts)).Methods("POST")
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
@@ -130,7 +156,7 @@ func TestFailedJSON(t *testing.T) {
 	ts := fixedTimestampCreator{}
 
 	router := mux.NewRouter()
-	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts)).Methods("POST")
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
 
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`i am not json`))
@@ -141,3 +167,293 @@ func TestFailedJSON(t *testing.T) {
 	connection.AssertExpectations(t)
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestWriteContentIfMatch(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("WriteConditionally",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{},
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417}),
+		db.IfLatest(1)).
+		Return(nil)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("If-Match", `"1"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"`+Hash(`{}`)+`"`, w.Header().Get("ETag"))
+}
+
+func TestWriteContentIfMatchContentHash(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("WriteConditionally",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{},
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417}),
+		db.Precondition{IfMatch: &db.RevisionCondition{Revisions: []int64{}, Hashes: []string{titleHash}}}).
+		Return(nil)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("If-Match", `"`+titleHash+`"`)
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWriteContentPreconditionFailed(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("WriteConditionally",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{},
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417}),
+		db.Precondition{IfNoneMatch: &db.RevisionCondition{Any: true, Revisions: []int64{}}}).
+		Return(db.ErrPreconditionFailed)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("If-None-Match", "*")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+}
+
+func TestWriteContentSkipsUnchangedContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+	latest := hashed(mapper.Wrap(map[string]interface{}{"title": "Unchanged", "body": "text"}, "a-real-uuid", "application/json", "methode", "1", 1436773875771421000))
+	latest.Content = nil
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(latest, true, nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, map[string]bool{"universal-content": true})).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{"body": "text", "title": "Unchanged"}`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Origin-System-Id", "methode")
+	req.Header.Add(SchemaVersionHeader, "1")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1436773875771421000", w.Header().Get(ContentRevisionHeader))
+	assert.Equal(t, `"`+latest.ContentHash+`"`, w.Header().Get(ETagHeader))
+}
+
+func TestWriteContentWritesChangedContent(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(mapper.Wrap(nil, "a-real-uuid", "application/json", "methode", "1", 1436773875771421000), true, nil)
+	connection.On("Read", "universal-content", "a-real-uuid").
+		Return(mapper.Wrap(map[string]interface{}{"title": "Unchanged"}, "a-real-uuid", "application/json", "methode", "1", 1436773875771421000), true, nil)
+	connection.On("Write", "universal-content", hashed(mapper.Wrap(map[string]interface{}{"title": "Unchanged"}, "a-real-uuid", "application/json", "methode", "2", 1436773875771421417))).
+		Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, map[string]bool{"universal-content": true})).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{"title": "Unchanged"}`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Origin-System-Id", "methode")
+	req.Header.Add(SchemaVersionHeader, "2")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"`+Hash(`{"title":"Unchanged"}`)+`"`, w.Header().Get(ETagHeader))
+}
+
+func TestWriteContentWithNumberOutOfCanonicalRange(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+	latest := hashed(mapper.Wrap(map[string]interface{}{"a": json.Number("2E400")}, "a-real-uuid", "application/json", "methode", "1", 1436773875771421000))
+	connection.On("ReadMetadata", "universal-content", "a-real-uuid").
+		Return(latest, true, nil)
+	written := hashed(mapper.Wrap(map[string]interface{}{"a": json.Number("1E400")}, "a-real-uuid", "application/json", "methode", "1", 1436773875771421417))
+	assert.Empty(t, written.CanonicalHash)
+	connection.On("Write", "universal-content", written).
+		Return(nil)
+
+	ts := fixedTimestampCreator{}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &ts, map[string]bool{"universal-content": true})).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{"a":1E400}`))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Origin-System-Id", "methode")
+	req.Header.Add(SchemaVersionHeader, "1")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	connection.AssertNotCalled(t, "Read", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWriteBinaryContent(t *testing.T) {
+	image := []byte{0x89, 'P', 'N', 'G', 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0xff}
+
+	connection := new(MockConnection)
+	connection.On("Write",
+		"images",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         image,
+			ContentType:     "image/png",
+			ContentRevision: 1436773875771421417})).
+		Return(nil)
+	connection.On("Count", "images", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &fixedTimestampCreator{}, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/images/a-real-uuid", bytes.NewReader(image))
+	req.Header.Add("Content-Type", "image/png")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWriteXMLContent(t *testing.T) {
+	document := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<nitf xmlns="http://iptc.org/std/NITF/2006-10-18/" version="-//IPTC//DTD NITF 3.5//EN">
+  <body><p b="2" a="1">Text</p><!-- comment --></body>
+</nitf>
+`)
+
+	connection := new(MockConnection)
+	connection.On("Write",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         document,
+			ContentType:     "application/nitf+xml",
+			ContentRevision: 1436773875771421417})).
+		Return(nil)
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &fixedTimestampCreator{}, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", bytes.NewReader(document))
+	req.Header.Add("Content-Type", "application/nitf+xml")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestWriteMalformedXMLContent(t *testing.T) {
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &fixedTimestampCreator{}, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`<doc><p></doc>`))
+	req.Header.Add("Content-Type", "application/xml")
+
+	router.ServeHTTP(w, req)
+	connection.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWriteContentWithInvalidContentType(t *testing.T) {
+	connection := new(MockConnection)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &fixedTimestampCreator{}, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`text`))
+	req.Header.Add("Content-Type", "not a media type")
+
+	router.ServeHTTP(w, req)
+	connection.AssertNotCalled(t, "Write", mock.Anything, mock.Anything)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWriteContentTooLarge(t *testing.T) {
+	connection := new(MockConnection)
+	connection.On("Write",
+		"universal-content",
+		hashed(&mapper.Resource{
+			UUID:            "a-real-uuid",
+			Content:         map[string]interface{}{},
+			ContentType:     "application/json",
+			ContentRevision: 1436773875771421417})).
+		Return(fmt.Errorf("%w: 70000000 bytes, the maximum is 67108864 bytes", db.ErrContentTooLarge))
+	connection.On("Count", "universal-content", "a-real-uuid", int64(1436773875771421417)).
+		Return(0, nil)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{collection}/{resource}", WriteContent(connection, &fixedTimestampCreator{}, nil)).Methods("POST")
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/universal-content/a-real-uuid", strings.NewReader(`{}`))
+	req.Header.Add("Content-Type", "application/json")
+
+	router.ServeHTTP(w, req)
+	connection.AssertExpectations(t)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
@@ -0,0 +1,215 @@
+// Package textdiff renders line based differences between two texts in the unified diff format.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	contextLines = 3
+	// maxEdits bounds the work spent looking for the shortest edit script, larger changes are shown as a full rewrite
+	maxEdits = 1000
+)
+
+type editKind byte
+
+const (
+	same    editKind = ' '
+	removed editKind = '-'
+	added   editKind = '+'
+)
+
+type edit struct {
+	kind editKind
+	line string
+	// positions of the line in the old and new text, counted from zero
+	oldPos, newPos int
+}
+
+// Unified returns the unified diff between the lines of two texts, or an empty string if they are the same
+func Unified(fromName, toName string, from, to []string) string {
+	edits := editScript(from, to)
+
+	var sb strings.Builder
+	for _, hunk := range hunks(edits) {
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		writeHunk(&sb, hunk)
+	}
+	return sb.String()
+}
+
+// editScript finds the shortest edit script with the Myers algorithm, after trimming the common prefix and suffix
+func editScript(from, to []string) []edit {
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(from)+len(to))
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{kind: same, line: from[i], oldPos: i, newPos: i})
+	}
+	edits = append(edits, myers(from[prefix:len(from)-suffix], to[prefix:len(to)-suffix], prefix, prefix)...)
+	for i := 0; i < suffix; i++ {
+		oldPos, newPos := len(from)-suffix+i, len(to)-suffix+i
+		edits = append(edits, edit{kind: same, line: from[oldPos], oldPos: oldPos, newPos: newPos})
+	}
+	return edits
+}
+
+func myers(a, b []string, oldOffset, newOffset int) []edit {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxEdits {
+		limit = maxEdits
+	}
+
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, offset, oldOffset, newOffset)
+			}
+		}
+	}
+
+	return rewrite(a, b, oldOffset, newOffset)
+}
+
+func backtrack(a, b []string, trace [][]int, offset, oldOffset, newOffset int) []edit {
+	x, y := len(a), len(b)
+	var reversed []edit
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, edit{kind: same, line: a[x], oldPos: oldOffset + x, newPos: newOffset + y})
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, edit{kind: added, line: b[prevY], oldPos: oldOffset + prevX, newPos: newOffset + prevY})
+			} else {
+				reversed = append(reversed, edit{kind: removed, line: a[prevX], oldPos: oldOffset + prevX, newPos: newOffset + prevY})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	edits := make([]edit, len(reversed))
+	for i := range reversed {
+		edits[i] = reversed[len(reversed)-1-i]
+	}
+	return edits
+}
+
+func rewrite(a, b []string, oldOffset, newOffset int) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for i, line := range a {
+		edits = append(edits, edit{kind: removed, line: line, oldPos: oldOffset + i, newPos: newOffset})
+	}
+	for i, line := range b {
+		edits = append(edits, edit{kind: added, line: line, oldPos: oldOffset + len(a), newPos: newOffset + i})
+	}
+	return edits
+}
+
+// hunks groups the changes with their surrounding context, merging changes whose context overlaps
+func hunks(edits []edit) [][]edit {
+	var result [][]edit
+	start, end := -1, -1
+
+	for i, e := range edits {
+		if e.kind == same {
+			continue
+		}
+
+		from := i - contextLines
+		if from < 0 {
+			from = 0
+		}
+		to := i + contextLines + 1
+		if to > len(edits) {
+			to = len(edits)
+		}
+
+		if start >= 0 && from <= end {
+			end = to
+			continue
+		}
+		if start >= 0 {
+			result = append(result, edits[start:end])
+		}
+		start, end = from, to
+	}
+
+	if start >= 0 {
+		result = append(result, edits[start:end])
+	}
+	return result
+}
+
+func writeHunk(sb *strings.Builder, hunk []edit) {
+	oldStart, newStart := hunk[0].oldPos+1, hunk[0].newPos+1
+	oldCount, newCount := 0, 0
+	for _, e := range hunk {
+		if e.kind != added {
+			oldCount++
+		}
+		if e.kind != removed {
+			newCount++
+		}
+	}
+
+	// an empty range starts at the line before it
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, e := range hunk {
+		sb.WriteByte(byte(e.kind))
+		sb.WriteString(e.line)
+		sb.WriteByte('\n')
+	}
+}
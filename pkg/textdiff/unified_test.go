@@ -0,0 +1,99 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lines(s string) []string {
+	return strings.Split(s, "\n")
+}
+
+func TestUnified(t *testing.T) {
+	var tests = []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name:     "no changes",
+			from:     "a\nb\nc",
+			to:       "a\nb\nc",
+			expected: "",
+		},
+		{
+			name: "changed line",
+			from: "a\nb\nc\nd\ne\nf\ng\nh\ni",
+			to:   "a\nb\nc\nd\nx\nf\ng\nh\ni",
+			expected: `--- from
++++ to
+@@ -2,7 +2,7 @@
+ b
+ c
+ d
+-e
++x
+ f
+ g
+ h
+`,
+		},
+		{
+			name: "separate hunks",
+			from: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj",
+			to:   "x\na\nb\nc\nd\ne\nf\ng\nh\ni",
+			expected: `--- from
++++ to
+@@ -1,3 +1,4 @@
++x
+ a
+ b
+ c
+@@ -7,4 +8,3 @@
+ g
+ h
+ i
+-j
+`,
+		},
+		{
+			name: "everything replaced",
+			from: "a\nb",
+			to:   "c",
+			expected: `--- from
++++ to
+@@ -1,2 +1,1 @@
+-a
+-b
++c
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, Unified("from", "to", lines(test.from), lines(test.to)))
+		})
+	}
+}
+
+func TestUnifiedFromEmptyText(t *testing.T) {
+	assert.Equal(t, "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n", Unified("from", "to", nil, []string{"a", "b"}))
+}
+
+func TestUnifiedRewriteForLargeChanges(t *testing.T) {
+	from := make([]string, maxEdits)
+	to := make([]string, maxEdits)
+	for i := range from {
+		from[i] = "a"
+		to[i] = "b"
+	}
+
+	diff := Unified("from", "to", from, to)
+
+	assert.Equal(t, maxEdits, strings.Count(diff, "\n-a"))
+	assert.Equal(t, maxEdits, strings.Count(diff, "\n+b"))
+}